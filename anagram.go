@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/domino14/word-golib/tilemapping"
+)
+
+// AnagramSearchRequest is the body of /find-anagrams and /find-subanagrams.
+// Letters holds up to 15 tiles, "?" for a blank. A word that can be made
+// with the blanks standing for different letters (AT from "A?T" as A-T,
+// ?-T, or A-?) is listed once, with as few blanks as possible, unless
+// ExpandBlanks is set. Sort is "alphabetical" (the default) or
+// "probability", which orders words by length and then most likely draw
+// first.
+type AnagramSearchRequest struct {
+	Letters          string `json:"letters"`
+	Lexicon          string `json:"lexicon,omitempty"`
+	ExpandBlanks     bool   `json:"expandBlanks,omitempty"`
+	Sort             string `json:"sort,omitempty"`
+	IncludeStudyInfo bool   `json:"includeStudyInfo,omitempty"` // add each word's alphagram and probability
+}
+
+// AnagramResult is a word found from the letters, with the letters the
+// blanks were assigned, in alphabetical order, and its StudyInfo if it was
+// asked for.
+type AnagramResult struct {
+	Word   string   `json:"word"`
+	Blanks []string `json:"blanks"`
+	*StudyInfo
+
+	tiles tilemapping.MachineWord // the word as placed, blanks included
+}
+
+type AnagramSearchResponse struct {
+	Letters  string          `json:"letters"`
+	Anagrams []AnagramResult `json:"anagrams"`
+	Count    int             `json:"count"`
+	Lexicon  string          `json:"lexicon"`
+}
+
+type SubanagramSearchResponse struct {
+	Letters     string          `json:"letters"`
+	Subanagrams []AnagramResult `json:"subanagrams"`
+	Count       int             `json:"count"`
+	Lexicon     string          `json:"lexicon"`
+}
+
+func findSubanagramsHandler(w http.ResponseWriter, r *http.Request) {
+	req, lex, letters, tiles, ok := readAnagramRequest(w, r)
+	if !ok {
+		return
+	}
+	results := findAnagrams(lex, tiles, false, req.ExpandBlanks)
+	orderAnagrams(lex, results, req)
+
+	response := SubanagramSearchResponse{
+		Letters:     letters,
+		Subanagrams: results,
+		Count:       len(results),
+		Lexicon:     lex.name,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func findAnagramsHandler(w http.ResponseWriter, r *http.Request) {
+	req, lex, letters, tiles, ok := readAnagramRequest(w, r)
+	if !ok {
+		return
+	}
+	results := findAnagrams(lex, tiles, true, req.ExpandBlanks)
+	orderAnagrams(lex, results, req)
+
+	response := AnagramSearchResponse{
+		Letters:  letters,
+		Anagrams: results,
+		Count:    len(results),
+		Lexicon:  lex.name,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// readAnagramRequest decodes and checks the body shared by the anagram
+// endpoints, returning the letters uppercased with spaces removed and as
+// machine letters. If the request is bad it writes the error and returns
+// false.
+func readAnagramRequest(w http.ResponseWriter, r *http.Request) (*AnagramSearchRequest, *lexicon, string, tilemapping.MachineWord, bool) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return nil, nil, "", nil, false
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, nil, "", nil, false
+	}
+
+	var req AnagramSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return nil, nil, "", nil, false
+	}
+	if req.Letters == "" {
+		http.Error(w, "Letters are required", http.StatusBadRequest)
+		return nil, nil, "", nil, false
+	}
+	if req.Sort != "" && req.Sort != "alphabetical" && req.Sort != "probability" {
+		http.Error(w, "sort must be \"alphabetical\" or \"probability\"", http.StatusBadRequest)
+		return nil, nil, "", nil, false
+	}
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, "", nil, false
+	}
+	letters := strings.ToUpper(strings.ReplaceAll(req.Letters, " ", ""))
+	tiles, err := tilemapping.ToMachineLetters(letters, lex.alph)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid letters %q", req.Letters), http.StatusBadRequest)
+		return nil, nil, "", nil, false
+	}
+	if len(tiles) > maxWordLength {
+		http.Error(w, fmt.Sprintf("at most %d letters are allowed", maxWordLength), http.StatusBadRequest)
+		return nil, nil, "", nil, false
+	}
+	return &req, lex, letters, tiles, true
+}
+
+// findAnagrams returns the words in lex that can be made from tiles (0 for
+// a blank), using all of them if exact is set, sorted by word. See
+// AnagramSearchRequest for what expand does.
+func findAnagrams(lex *lexicon, tiles tilemapping.MachineWord, exact, expand bool) []AnagramResult {
+	a := &anagrammer{
+		lex:       lex,
+		freq:      make([]int, lex.alph.NumLetters()),
+		remaining: len(tiles),
+		exact:     exact,
+		expand:    expand,
+		seen:      map[string]bool{},
+		results:   []AnagramResult{},
+	}
+	for _, ml := range tiles {
+		if ml == 0 {
+			a.blanks++
+		} else {
+			a.freq[ml]++
+		}
+	}
+	a.walk(lex.gd.ArcIndex(0))
+	return sortAnagrams(a.results, a.expand)
+}
+
+// anagrammer walks the DAWG half of a KWG, spending a multiset of tiles on
+// the letters along each path, and records every word the path spells.
+type anagrammer struct {
+	lex       *lexicon
+	freq      []int // natural tiles left, by machine letter
+	blanks    int   // blanks left
+	remaining int   // tiles left, blanks included
+	exact     bool
+	expand    bool
+	word      tilemapping.MachineWord
+	seen      map[string]bool
+	results   []AnagramResult
+}
+
+// walk tries each arc in the sibling list starting at node. A letter is
+// taken from a natural tile if one is left, and otherwise from a blank;
+// with expand set, a blank is tried even when a natural tile is left.
+func (a *anagrammer) walk(node uint32) {
+	for ; ; node++ {
+		ml := tilemapping.MachineLetter(a.lex.gd.Tile(node))
+		if a.freq[ml] > 0 {
+			a.freq[ml]--
+			a.follow(node, ml)
+			a.freq[ml]++
+		}
+		if a.blanks > 0 && (a.freq[ml] == 0 || a.expand) {
+			a.blanks--
+			a.follow(node, ml.Blank())
+			a.blanks++
+		}
+		if a.lex.gd.IsEnd(node) {
+			return
+		}
+	}
+}
+
+// follow places ml on the word, records the word if node ends one, and
+// continues below node.
+func (a *anagrammer) follow(node uint32, ml tilemapping.MachineLetter) {
+	a.word = append(a.word, ml)
+	a.remaining--
+	if a.lex.gd.Accepts(node) && (!a.exact || a.remaining == 0) {
+		a.record()
+	}
+	if next := a.lex.gd.ArcIndex(node); next != 0 && a.remaining > 0 {
+		a.walk(next)
+	}
+	a.remaining++
+	a.word = a.word[:len(a.word)-1]
+}
+
+// record adds the current word, unless it was already found with the same
+// blank letters.
+func (a *anagrammer) record() {
+	var word strings.Builder
+	blanks := []string{}
+	for _, ml := range a.word {
+		word.WriteString(a.lex.alph.Letter(ml.Unblank()))
+		if ml.IsBlanked() {
+			blanks = append(blanks, a.lex.alph.Letter(ml.Unblank()))
+		}
+	}
+	sort.Strings(blanks)
+	result := AnagramResult{Word: word.String(), Blanks: blanks, tiles: append(tilemapping.MachineWord(nil), a.word...)}
+	if key := result.Word + ":" + strings.Join(blanks, ""); !a.seen[key] {
+		a.seen[key] = true
+		a.results = append(a.results, result)
+	}
+}
+
+// orderAnagrams puts results, which are in alphabetical order, in the order
+// req asks for and adds their StudyInfo if it asks for that.
+func orderAnagrams(lex *lexicon, results []AnagramResult, req *AnagramSearchRequest) {
+	if req.Sort == "probability" {
+		less := probabilityLess(lex.ld)
+		sort.SliceStable(results, func(i, j int) bool { return less(results[i].tiles, results[j].tiles) })
+	}
+	if req.IncludeStudyInfo {
+		for i := range results {
+			results[i].StudyInfo = studyInfo(lex, results[i].tiles)
+		}
+	}
+}
+
+// sortAnagrams sorts results by word and then by how many blanks they use,
+// and unless expand is set keeps only the first way of making each word.
+func sortAnagrams(results []AnagramResult, expand bool) []AnagramResult {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Word != b.Word {
+			return a.Word < b.Word
+		}
+		if len(a.Blanks) != len(b.Blanks) {
+			return len(a.Blanks) < len(b.Blanks)
+		}
+		return strings.Join(a.Blanks, "") < strings.Join(b.Blanks, "")
+	})
+	if expand {
+		return results
+	}
+	collapsed := results[:0]
+	for _, result := range results {
+		if len(collapsed) == 0 || result.Word != collapsed[len(collapsed)-1].Word {
+			collapsed = append(collapsed, result)
+		}
+	}
+	return collapsed
+}
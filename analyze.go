@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/domino14/word-golib/kwg"
+	"github.com/domino14/word-golib/tilemapping"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/cross_set"
+	"github.com/domino14/macondo/game"
+	"github.com/domino14/macondo/gcgio"
+	pb "github.com/domino14/macondo/gen/api/proto/macondo"
+	"github.com/domino14/macondo/move"
+	"github.com/domino14/macondo/movegen"
+)
+
+type AnalyzeGameRequest struct {
+	GCG     string `json:"gcg"`               // Contents of a GCG game file
+	Lexicon string `json:"lexicon,omitempty"` // defaults to the GCG's #lexicon if loaded, else NWL23
+}
+
+// AnalyzedTurn is one turn of the game as played, next to the best play the
+// generator finds for the same rack and board. Best and EquityLost only
+// consider tile placements; an exchange or pass that beats every placement
+// loses nothing. Phony is judged against the analysis lexicon, and Withdrawn
+// marks a play that was challenged off the board.
+type AnalyzedTurn struct {
+	Turn       int      `json:"turn"`
+	Player     string   `json:"player"`
+	Rack       string   `json:"rack"`
+	Action     string   `json:"action"`         // "play", "exchange", or "pass"
+	Move       *Move    `json:"move,omitempty"` // omitted for exchanges and passes
+	Exchanged  string   `json:"exchanged,omitempty"`
+	Score      int      `json:"score"`
+	Equity     float64  `json:"equity"`
+	Best       *Move    `json:"best,omitempty"`
+	EquityLost float64  `json:"equityLost"`
+	Phony      bool     `json:"phony"`
+	PhonyWords []string `json:"phonyWords,omitempty"`
+	Withdrawn  bool     `json:"withdrawn,omitempty"`
+}
+
+type AnalyzeGameResponse struct {
+	Players    []string       `json:"players"`
+	Turns      []AnalyzedTurn `json:"turns"`
+	EquityLost []float64      `json:"equityLost"` // total per player, in Players order
+	Lexicon    string         `json:"lexicon"`
+}
+
+// gcgTurnLine matches a GCG event line. The GCG parser can't cope with a
+// file that has none, so that is rejected up front.
+var gcgTurnLine = regexp.MustCompile(`(?m)^>`)
+
+func analyzeGameHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req AnalyzeGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !gcgTurnLine.MatchString(req.GCG) {
+		http.Error(w, "GCG must contain at least one turn", http.StatusBadRequest)
+		return
+	}
+
+	hist, err := gcgio.ParseGCGFromReader(serviceConfig, strings.NewReader(req.GCG))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid GCG: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	lexName := req.Lexicon
+	if lexName == "" {
+		if _, ok := lexica[strings.ToUpper(hist.Lexicon)]; ok {
+			lexName = hist.Lexicon
+		}
+	}
+	lex, err := getLexicon(lexName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fmt.Printf("Analyzing game with %d events\n", len(hist.Events))
+
+	bd := board.MakeBoard(board.CrosswordGameBoard)
+	cross_set.GenAllCrossSets(bd, lex.gd, lex.ld)
+	bd.UpdateAllAnchors()
+	generator := movegen.NewGordonGenerator(lex.gd, bd, lex.ld)
+
+	response := AnalyzeGameResponse{
+		Turns:      []AnalyzedTurn{},
+		EquityLost: make([]float64, len(hist.Players)),
+		Lexicon:    lex.name,
+	}
+	for _, p := range hist.Players {
+		response.Players = append(response.Players, p.Nickname)
+	}
+
+	for i, evt := range hist.Events {
+		switch evt.Type {
+		case pb.GameEvent_TILE_PLACEMENT_MOVE, pb.GameEvent_EXCHANGE, pb.GameEvent_PASS:
+		default:
+			continue
+		}
+		withdrawn := i+1 < len(hist.Events) && hist.Events[i+1].Type == pb.GameEvent_PHONY_TILES_RETURNED
+
+		turn, m, err := analyzeTurn(lex, bd, generator, evt)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("turn %d: %v", len(response.Turns)+1, err), http.StatusBadRequest)
+			return
+		}
+		turn.Turn = len(response.Turns) + 1
+		turn.Player = response.Players[evt.PlayerIndex]
+		turn.Withdrawn = withdrawn
+		response.Turns = append(response.Turns, turn)
+		response.EquityLost[evt.PlayerIndex] += turn.EquityLost
+
+		if m.Action() == move.MoveTypePlay && !withdrawn {
+			bd.PlayMove(m)
+			cross_set.UpdateCrossSetsForMove(bd, m, lex.gd, lex.ld)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// analyzeTurn compares the move recorded in evt with the best-equity play
+// available on bd. The bag is taken to hold every tile unseen from the
+// mover's side except a full opponent rack. It returns the move made so the
+// caller can put it on the board.
+func analyzeTurn(lex *lexicon, bd *board.GameBoard, generator *movegen.GordonGenerator,
+	evt *pb.GameEvent) (AnalyzedTurn, *move.Move, error) {
+
+	turn := AnalyzedTurn{Rack: evt.Rack}
+	m, err := game.MoveFromEvent(evt, lex.alph, bd)
+	if err != nil {
+		return turn, nil, err
+	}
+	counts, err := unseenTiles(lex, bd, evt.Rack)
+	if err != nil {
+		return turn, nil, err
+	}
+	bagSize := -rackSize
+	for _, n := range counts {
+		bagSize += n
+	}
+	if bagSize < 0 {
+		bagSize = 0
+	}
+	bag := bagWithTiles(lex, bagSize)
+
+	setEquities([]*move.Move{m}, bd, lex, bag)
+	turn.Score = m.Score()
+	turn.Equity = m.Equity()
+	switch m.Action() {
+	case move.MoveTypePlay:
+		turn.Action = "play"
+		played := moveToResponse(m, bd, lex)
+		turn.Move = &played
+		turn.PhonyWords, err = phonyWords(lex, bd, m)
+		if err != nil {
+			return turn, nil, err
+		}
+		turn.Phony = len(turn.PhonyWords) > 0
+	case move.MoveTypeExchange:
+		turn.Action = "exchange"
+		turn.Exchanged = m.Tiles().UserVisible(lex.alph)
+	default:
+		turn.Action = "pass"
+	}
+
+	rack := tilemapping.RackFromString(evt.Rack, lex.alph)
+	if best := bestStaticMove(generator.GenAll(rack, false), bd, lex, bagSize); best != nil {
+		resp := moveToResponse(best, bd, lex)
+		turn.Best = &resp
+		if lost := best.Equity() - m.Equity(); lost > 0 {
+			turn.EquityLost = lost
+		}
+	}
+	return turn, m, nil
+}
+
+// phonyWords returns the words formed by m on bd that are not in lex.
+func phonyWords(lex *lexicon, bd *board.GameBoard, m *move.Move) ([]string, error) {
+	words, err := bd.FormedWords(m)
+	if err != nil {
+		return nil, err
+	}
+	var phonies []string
+	for _, w := range words {
+		if len(w) > 1 && !kwg.FindMachineWord(lex.gd, w) {
+			phonies = append(phonies, w.UserVisible(lex.alph))
+		}
+	}
+	return phonies, nil
+}
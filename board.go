@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/cross_set"
+)
+
+// boardSize is the width and height of a standard board.
+const boardSize = 15
+
+// BoardCell is one square of a request board. In JSON it is either a string
+// ("" for empty, "A" for a tile, "a" for a blank played as A) or an object
+// like {"letter": "A", "blank": true}.
+type BoardCell struct {
+	Letter string `json:"letter"`
+	Blank  bool   `json:"blank,omitempty"`
+}
+
+func (c *BoardCell) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*c = BoardCell{Letter: s}
+		return nil
+	}
+	// Decode through a distinct type so this method isn't called again.
+	type cell BoardCell
+	var obj cell
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return errors.New("board cells must be strings or {letter, blank} objects")
+	}
+	*c = BoardCell(obj)
+	return nil
+}
+
+// tile returns the cell in the single-string form the alphabet understands,
+// with blanks as lowercase letters.
+func (c BoardCell) tile() string {
+	if c.Blank {
+		return strings.ToLower(c.Letter)
+	}
+	return c.Letter
+}
+
+// squareName names a 0-indexed square the way players do, e.g. "row 8,
+// column H".
+func squareName(row, col int) string {
+	return fmt.Sprintf("row %d, column %c", row+1, 'A'+col)
+}
+
+// squareCoords matches a square written as column letter and row number in
+// either order, e.g. "H8" or "8H".
+var squareCoords = regexp.MustCompile(`^(?:([A-Oa-o])(\d{1,2})|(\d{1,2})([A-Oa-o]))$`)
+
+// parseSquare returns the 0-indexed row and column of a square such as "H8"
+// or "8H".
+func parseSquare(s string) (int, int, error) {
+	row, col, _, err := parseCoords(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid square %q", s)
+	}
+	return row, col, nil
+}
+
+// parseCoords reads move coordinates: row number first for a play across
+// ("8H"), column letter first for one down ("H8").
+func parseCoords(s string) (row, col int, vertical bool, err error) {
+	m := squareCoords.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, 0, false, fmt.Errorf("invalid coordinates %q", s)
+	}
+	letter, number := m[1], m[2]
+	vertical = letter != ""
+	if !vertical {
+		letter, number = m[4], m[3]
+	}
+	row, _ = strconv.Atoi(number)
+	if row < 1 || row > boardSize {
+		return 0, 0, false, fmt.Errorf("invalid coordinates %q", s)
+	}
+	return row - 1, int(strings.ToUpper(letter)[0] - 'A'), vertical, nil
+}
+
+// checkBoardShape reports an error unless cells is a 15x15 grid.
+func checkBoardShape(cells [][]BoardCell) error {
+	if len(cells) != boardSize {
+		return errors.New("Board must have 15 rows")
+	}
+	for i := range cells {
+		if len(cells[i]) != boardSize {
+			return errors.New("Each board row must have 15 columns")
+		}
+	}
+	return nil
+}
+
+// loadBoard places the tiles in cells (which must already have passed
+// checkBoardShape) on a fresh board, and generates the cross-sets and
+// anchors the move generator needs. Blanks are placed as blanked machine
+// letters so they score zero. Any cell that isn't a single letter of the
+// lexicon's alphabet is an error.
+func loadBoard(cells [][]BoardCell, lex *lexicon) (*board.GameBoard, error) {
+	bd := board.MakeBoard(board.CrosswordGameBoard)
+
+	tilesPlayed := 0
+	for row := 0; row < boardSize; row++ {
+		for col := 0; col < boardSize; col++ {
+			tile := cells[row][col].tile()
+			if tile == "" {
+				continue
+			}
+			ml, err := lex.alph.Val(tile)
+			// Val maps "?" and "." to 0, which is an empty square, not a tile.
+			if err != nil || ml == 0 {
+				return nil, fmt.Errorf("invalid tile %q at %s", tile, squareName(row, col))
+			}
+			bd.SetLetter(row, col, ml)
+			tilesPlayed++
+		}
+	}
+
+	// SetLetter doesn't track the tiles-played count, so set it by hand.
+	bd.TestSetTilesPlayed(tilesPlayed)
+
+	cross_set.GenAllCrossSets(bd, lex.gd, lex.ld)
+	bd.UpdateAllAnchors()
+	return bd, nil
+}
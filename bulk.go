@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/move"
+	"github.com/domino14/macondo/movegen"
+)
+
+const (
+	// scoreBucketWidth is the width in points of each ScoreHistogram bucket.
+	scoreBucketWidth = 10
+	// maxTopPlays bounds how many of the most frequent top plays are listed.
+	maxTopPlays = 10
+	// maxBulkIterations bounds Iterations so one request can't run for
+	// ever.
+	maxBulkIterations = 100000
+)
+
+// ScorePercentiles are nearest-rank percentiles of the top play's score.
+type ScorePercentiles struct {
+	P10 int `json:"p10"`
+	P50 int `json:"p50"`
+	P90 int `json:"p90"`
+}
+
+// ScoreBucket counts the iterations whose top play scored at least Min and
+// at most Max points.
+type ScoreBucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Count int `json:"count"`
+}
+
+// PlayFrequency is how often a play (e.g. "8D HELLO") was the top play.
+type PlayFrequency struct {
+	Play    string  `json:"play"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// bulkSample is the outcome of one bulk iteration.
+type bulkSample struct {
+	evaluated bool   // the pool could fill a rack
+	noPlay    bool   // the rack had no legal play
+	score     int    // of the highest-scoring play
+	bingo     bool   // that play used the whole rack
+	play      string // that play, e.g. "8D HELLO"
+}
+
+// newBulkSample records top, the highest-scoring play on bd for an evaluated
+// iteration, or nil if the rack had no legal play.
+func newBulkSample(top *move.Move, bd *board.GameBoard, lex *lexicon) bulkSample {
+	if top == nil {
+		return bulkSample{evaluated: true, noPlay: true}
+	}
+	word, _ := playWord(top, bd, lex)
+	return bulkSample{
+		evaluated: true,
+		score:     top.Score(),
+		bingo:     top.TilesPlayed() == rackSize,
+		play:      top.BoardCoords() + " " + word,
+	}
+}
+
+// bulkStats accumulates the evaluated bulk iterations. An iteration with no
+// legal play counts as scoring zero.
+type bulkStats struct {
+	scores     []int
+	bingos     int
+	noPlay     int
+	playCounts map[string]int
+}
+
+func newBulkStats() *bulkStats {
+	return &bulkStats{playCounts: map[string]int{}}
+}
+
+// add records one iteration; ones that weren't evaluated are ignored.
+func (s *bulkStats) add(sample bulkSample) {
+	if !sample.evaluated {
+		return
+	}
+	s.scores = append(s.scores, sample.score)
+	if sample.noPlay {
+		s.noPlay++
+		return
+	}
+	if sample.bingo {
+		s.bingos++
+	}
+	s.playCounts[sample.play]++
+}
+
+// fill sets the statistics fields of resp from what has been added so far.
+func (s *bulkStats) fill(resp *BulkMoveGenResponse) {
+	n := len(s.scores)
+	resp.EvaluatedIterations = n
+	resp.TotalBingos = s.bingos
+	resp.TotalScore = 0
+	for _, score := range s.scores {
+		resp.TotalScore += score
+	}
+	resp.ScoreHistogram = []ScoreBucket{}
+	resp.TopPlays = []PlayFrequency{}
+	if n == 0 {
+		return
+	}
+
+	resp.AverageScore = float64(resp.TotalScore) / float64(n)
+	resp.BingoPercent = float64(s.bingos) / float64(n) * 100.0
+	resp.NoPlayPercent = float64(s.noPlay) / float64(n) * 100.0
+
+	sorted := append([]int(nil), s.scores...)
+	sort.Ints(sorted)
+	percentile := func(p int) int {
+		// Nearest rank: the smallest score at or above p% of iterations.
+		rank := (p*n + 99) / 100
+		if rank < 1 {
+			rank = 1
+		}
+		return sorted[rank-1]
+	}
+	resp.ScorePercentiles = ScorePercentiles{P10: percentile(10), P50: percentile(50), P90: percentile(90)}
+
+	for _, score := range sorted {
+		i := score / scoreBucketWidth
+		for len(resp.ScoreHistogram) <= i {
+			lo := len(resp.ScoreHistogram) * scoreBucketWidth
+			resp.ScoreHistogram = append(resp.ScoreHistogram, ScoreBucket{Min: lo, Max: lo + scoreBucketWidth - 1})
+		}
+		resp.ScoreHistogram[i].Count++
+	}
+
+	for play, count := range s.playCounts {
+		resp.TopPlays = append(resp.TopPlays, PlayFrequency{
+			Play:    play,
+			Count:   count,
+			Percent: float64(count) / float64(n) * 100.0,
+		})
+	}
+	sort.Slice(resp.TopPlays, func(i, j int) bool {
+		if resp.TopPlays[i].Count != resp.TopPlays[j].Count {
+			return resp.TopPlays[i].Count > resp.TopPlays[j].Count
+		}
+		return resp.TopPlays[i].Play < resp.TopPlays[j].Play
+	})
+	if len(resp.TopPlays) > maxTopPlays {
+		resp.TopPlays = resp.TopPlays[:maxTopPlays]
+	}
+}
+
+// bulkWorkers returns how many workers to run for a request asking for n:
+// one per usable CPU by default, and never more than that or than there are
+// iterations.
+func bulkWorkers(n, iterations int) int {
+	max := runtime.GOMAXPROCS(0)
+	if n <= 0 || n > max {
+		n = max
+	}
+	if n > iterations {
+		n = iterations
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// bulkRun is a checked /bulk-move-gen request, ready to run.
+type bulkRun struct {
+	lex        *lexicon
+	bd         *board.GameBoard
+	tilePool   string
+	iterations int
+	workers    int
+	seed       int64
+}
+
+// newBulkRun checks req, fills in its defaults, and loads its board and
+// tile pool.
+func newBulkRun(req *BulkMoveGenRequest) (*bulkRun, error) {
+	if req.TilePool == "" && !req.DeriveTilePool {
+		return nil, errors.New("TilePool is required")
+	}
+	if err := checkBoardShape(req.Board); err != nil {
+		return nil, err
+	}
+	if req.Iterations <= 0 {
+		req.Iterations = 1000
+	}
+	if req.Iterations > maxBulkIterations {
+		return nil, fmt.Errorf("at most %d iterations are allowed", maxBulkIterations)
+	}
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		return nil, err
+	}
+	bd, err := loadBoard(req.Board, lex)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert tile pool to uppercase and remove spaces
+	tilePool := strings.ToUpper(strings.ReplaceAll(req.TilePool, " ", ""))
+	if req.DeriveTilePool {
+		counts, err := unseenTiles(lex, bd, req.Rack)
+		if err != nil {
+			return nil, err
+		}
+		tilePool = poolString(lex, counts)
+	}
+
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	return &bulkRun{
+		lex:        lex,
+		bd:         bd,
+		tilePool:   tilePool,
+		iterations: req.Iterations,
+		workers:    bulkWorkers(req.Workers, req.Iterations),
+		seed:       seed,
+	}, nil
+}
+
+// run runs the iterations and returns their statistics. If progress is not
+// nil it is called after each iteration, one call at a time. If ctx is
+// cancelled the workers stop and ctx's error is returned.
+func (b *bulkRun) run(ctx context.Context, progress func(bulkSample)) (*BulkMoveGenResponse, error) {
+	samples, err := runBulkIterations(ctx, b.lex, b.bd, b.seed, b.tilePool, b.iterations, b.workers, progress)
+	if err != nil {
+		return nil, err
+	}
+	stats := newBulkStats()
+	for _, sample := range samples {
+		stats.add(sample)
+	}
+	return b.response(stats), nil
+}
+
+// response returns b's response with the statistics in stats.
+func (b *bulkRun) response(stats *bulkStats) *BulkMoveGenResponse {
+	resp := &BulkMoveGenResponse{
+		Iterations: b.iterations,
+		Seed:       b.seed,
+		Lexicon:    b.lex.name,
+	}
+	stats.fill(resp)
+	return resp
+}
+
+// runBulkIterations draws a random rack from tilePool for each of the given
+// number of iterations and finds its highest-scoring play on bd. The
+// iterations are shared among workers goroutines, each with its own copy of
+// the board and its own generator, and each iteration draws its rack from
+// an RNG seeded by iterationSeed. Samples are returned in iteration order,
+// so the result depends only on seed and not on how the work was
+// scheduled. progress, if not nil, sees each sample as it finishes; calls
+// to it are serialized.
+func runBulkIterations(ctx context.Context, lex *lexicon, bd *board.GameBoard, seed int64,
+	tilePool string, iterations, workers int, progress func(bulkSample)) ([]bulkSample, error) {
+
+	samples := make([]bulkSample, iterations)
+	var next atomic.Int64
+	var progressMu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wbd := bd.Copy()
+			generator := movegen.NewGordonGenerator(lex.gd, wbd, lex.ld)
+			rng := rand.New(rand.NewSource(0))
+			for ctx.Err() == nil {
+				i := int(next.Add(1)) - 1
+				if i >= iterations {
+					return
+				}
+				rng.Seed(iterationSeed(seed, i))
+				if rack := generateRandomRack(rng, tilePool, rackSize, lex.alph); rack != nil {
+					// Plays come back highest score first.
+					// GenAll returns a lone pass when there is no play.
+					var top *move.Move
+					if moves := generator.GenAll(rack, false); len(moves) > 0 && moves[0].Action() == move.MoveTypePlay {
+						top = moves[0]
+					}
+					samples[i] = newBulkSample(top, wbd, lex)
+				}
+				if progress != nil {
+					progressMu.Lock()
+					progress(samples[i])
+					progressMu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// iterationSeed derives the seed of iteration i's rack draw from the run's
+// seed, mixing the two (as SplitMix64 does) so that nearby seeds don't
+// share racks.
+func iterationSeed(seed int64, i int) int64 {
+	z := uint64(seed) + uint64(i+1)*0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return int64(z ^ z>>31)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// TestBulkRunDeterministic checks that a seeded run gives the same response
+// however many workers share it, and every time it is run.
+func TestBulkRunDeterministic(t *testing.T) {
+	lex := testLexicon(t, "NWL_BULK_TEST", "AB", "ABS", "BA", "BAD", "BADE", "BEAD", "DAB", "SAB", "SABE", "TAB", "TABS")
+	cells := make([][]BoardCell, boardSize)
+	for i := range cells {
+		cells[i] = make([]BoardCell, boardSize)
+	}
+	cells[7][7] = BoardCell{Letter: "A"}
+	cells[7][8] = BoardCell{Letter: "B"}
+	bd, err := loadBoard(cells, lex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(workers int) *BulkMoveGenResponse {
+		b := &bulkRun{
+			lex:        lex,
+			bd:         bd,
+			tilePool:   "AAABBDDEEESSTT??",
+			iterations: 200,
+			workers:    workers,
+			seed:       42,
+		}
+		resp, err := b.run(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	want := run(1)
+	if want.EvaluatedIterations != 200 {
+		t.Fatalf("evaluated %d iterations, want 200", want.EvaluatedIterations)
+	}
+	if got := run(4); !reflect.DeepEqual(got, want) {
+		t.Errorf("4 workers gave %+v, want %+v as from 1", got, want)
+	}
+	if got := run(1); !reflect.DeepEqual(got, want) {
+		t.Errorf("second run gave %+v, want %+v as from the first", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/domino14/macondo/board"
+)
+
+// cgpPosition is a position read from CGP (crossword game position)
+// notation, e.g.
+//
+//	15/15/15/15/15/15/15/5HELLO5/15/15/15/15/15/15/15 AERST?Q/ 0/0 0 lex NWL23;
+//
+// The board rows are separated by "/", runs of empty squares are written as
+// numbers, lowercase letters are blanks, and multi-character tiles are
+// bracketed. Then come the racks and scores of the player to move and the
+// opponent, the number of consecutive scoreless turns, and optional
+// ";"-terminated operations, of which only "lex" is used here.
+type cgpPosition struct {
+	Board          [][]BoardCell
+	Racks          [2]string
+	Scores         [2]int
+	ScorelessTurns int
+	Lexicon        string
+}
+
+// parseCGP parses a CGP string. Fields may be separated by any run of
+// whitespace. The board cells are not checked against an alphabet here;
+// loadBoard does that once the lexicon is known.
+func parseCGP(s string) (*cgpPosition, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return nil, errors.New("CGP must have a board, racks, scores, and a scoreless turn count")
+	}
+
+	pos := &cgpPosition{}
+	rows := strings.Split(fields[0], "/")
+	if len(rows) != boardSize {
+		return nil, fmt.Errorf("CGP board must have %d rows, got %d", boardSize, len(rows))
+	}
+	for i, row := range rows {
+		cells, err := cgpRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("CGP row %d: %v", i+1, err)
+		}
+		pos.Board = append(pos.Board, cells)
+	}
+
+	racks := strings.Split(fields[1], "/")
+	scores := strings.Split(fields[2], "/")
+	if len(racks) != 2 || len(scores) != 2 {
+		return nil, errors.New("CGP must have two racks and two scores")
+	}
+	for i := range racks {
+		pos.Racks[i] = racks[i]
+		score, err := strconv.Atoi(scores[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CGP score %q", scores[i])
+		}
+		pos.Scores[i] = score
+	}
+
+	zeros, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CGP scoreless turn count %q", fields[3])
+	}
+	pos.ScorelessTurns = zeros
+
+	if len(fields) > 4 {
+		ops := strings.Join(fields[4:], " ")
+		for _, op := range strings.Split(ops, ";") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(op), " ")
+			if name == "lex" {
+				pos.Lexicon = strings.TrimSpace(arg)
+			}
+		}
+	}
+	return pos, nil
+}
+
+// cgpRow expands one run-length encoded CGP board row into cells.
+func cgpRow(row string) ([]BoardCell, error) {
+	cells := make([]BoardCell, 0, boardSize)
+	runes := []rune(row)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			n, _ := strconv.Atoi(string(runes[i:j]))
+			for k := 0; k < n; k++ {
+				cells = append(cells, BoardCell{})
+			}
+			i = j - 1
+		case r == '[':
+			j := i + 1
+			for j < len(runes) && runes[j] != ']' {
+				j++
+			}
+			if j == len(runes) {
+				return nil, errors.New("unterminated [ tile")
+			}
+			cells = append(cells, BoardCell{Letter: string(runes[i+1 : j])})
+			i = j
+		default:
+			cells = append(cells, BoardCell{Letter: string(r)})
+		}
+	}
+	if len(cells) != boardSize {
+		return nil, fmt.Errorf("must have %d squares, got %d", boardSize, len(cells))
+	}
+	return cells, nil
+}
+
+// toCGP writes a position on bd as a CGP string.
+func toCGP(bd *board.GameBoard, lex *lexicon, racks [2]string, scores [2]int, scorelessTurns int) string {
+	return fmt.Sprintf("%s %s/%s %d/%d %d lex %s;",
+		bd.ToFEN(lex.alph), racks[0], racks[1], scores[0], scores[1], scorelessTurns, lex.name)
+}
+
+// applyCGP parses cgp, if one was given, and uses it for a request's board
+// and for its rack and lexicon when those weren't set explicitly. It
+// returns the parsed position, or an empty one when cgp is "".
+func applyCGP(cgp string, cells *[][]BoardCell, rack, lexicon *string) (*cgpPosition, error) {
+	if cgp == "" {
+		return &cgpPosition{}, nil
+	}
+	pos, err := parseCGP(cgp)
+	if err != nil {
+		return nil, err
+	}
+	*cells = pos.Board
+	if rack != nil && *rack == "" {
+		*rack = pos.Racks[0]
+	}
+	if *lexicon == "" {
+		*lexicon = pos.Lexicon
+	}
+	return pos, nil
+}
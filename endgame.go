@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/domino14/word-golib/tilemapping"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/cross_set"
+	"github.com/domino14/macondo/move"
+	"github.com/domino14/macondo/movegen"
+)
+
+// maxEndgamePlies bounds MaxPlies so a request can't ask for an unbounded
+// search.
+const maxEndgamePlies = 25
+
+type SolveEndgameRequest struct {
+	Board       [][]BoardCell `json:"board"`                 // 15x15 board; lowercase letters are blanks
+	Rack        string        `json:"rack"`                  // Rack of the player to move
+	OppRack     string        `json:"oppRack"`               // Opponent's rack
+	Score       int           `json:"score"`                 // Score of the player to move
+	OppScore    int           `json:"oppScore"`              // Opponent's score
+	MaxPlies    int           `json:"maxPlies,omitempty"`    // Search depth limit (default 8)
+	TimeLimitMs int           `json:"timeLimitMs,omitempty"` // Search time limit (default 5000)
+	Lexicon     string        `json:"lexicon,omitempty"`
+	CGP         string        `json:"cgp,omitempty"` // Position in CGP notation; replaces board
+}
+
+// EndgamePly is one move of the principal variation. Move is omitted for
+// passes.
+type EndgamePly struct {
+	Player string `json:"player"` // "us" or "opponent"
+	Pass   bool   `json:"pass,omitempty"`
+	Move   *Move  `json:"move,omitempty"`
+}
+
+// SolveEndgameResponse reports the best line found. FinalSpread is the
+// mover's score minus the opponent's at the end of that line. Completed is
+// true only when the search reached the end of the game on every line it
+// considered, i.e. the result is exact rather than cut off by MaxPlies or
+// the time limit.
+type SolveEndgameResponse struct {
+	Variation   []EndgamePly `json:"variation"`
+	FinalSpread int          `json:"finalSpread"`
+	Completed   bool         `json:"completed"`
+	Depth       int          `json:"depth"` // deepest fully searched ply count
+	Nodes       int          `json:"nodes"`
+	Lexicon     string       `json:"lexicon"`
+}
+
+func solveEndgameHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SolveEndgameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	pos, err := applyCGP(req.CGP, &req.Board, &req.Rack, &req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.CGP != "" {
+		if req.OppRack == "" {
+			req.OppRack = pos.Racks[1]
+		}
+		if req.Score == 0 && req.OppScore == 0 {
+			req.Score, req.OppScore = pos.Scores[0], pos.Scores[1]
+		}
+	}
+
+	if req.Rack == "" || req.OppRack == "" {
+		http.Error(w, "Rack and OppRack are required", http.StatusBadRequest)
+		return
+	}
+	if err := checkBoardShape(req.Board); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.MaxPlies <= 0 {
+		req.MaxPlies = 8
+	}
+	if req.MaxPlies > maxEndgamePlies {
+		req.MaxPlies = maxEndgamePlies
+	}
+	if req.TimeLimitMs <= 0 {
+		req.TimeLimitMs = 5000
+	}
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bd, err := loadBoard(req.Board, lex)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var racks [2]*tilemapping.Rack
+	for i, letters := range [2]string{req.Rack, req.OppRack} {
+		tiles, err := tilemapping.ToMachineLetters(strings.ToUpper(strings.ReplaceAll(letters, " ", "")), lex.alph)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid rack %q", letters), http.StatusBadRequest)
+			return
+		}
+		racks[i] = tilemapping.NewRack(lex.alph)
+		racks[i].Set(tiles)
+	}
+	solver := newEndgameSolver(lex, bd, req.MaxPlies, racks[0], racks[1])
+
+	fmt.Printf("Solving endgame for '%s' vs '%s' (max %d plies, %dms)\n",
+		req.Rack, req.OppRack, req.MaxPlies, req.TimeLimitMs)
+	value, pv, depth, completed := solver.solve(time.Duration(req.TimeLimitMs) * time.Millisecond)
+
+	// Replay the variation on a copy of the board so each play's words can
+	// include the tiles it plays through and hooks.
+	variation := make([]EndgamePly, 0, len(pv))
+	replay := bd.Copy()
+	for i, m := range pv {
+		ply := EndgamePly{Player: "us"}
+		if i%2 == 1 {
+			ply.Player = "opponent"
+		}
+		if m.Action() == move.MoveTypePlay {
+			m.SetEquity(float64(m.Score()))
+			resp := moveToResponse(m, replay, lex)
+			ply.Move = &resp
+			replay.PlayMove(m)
+			cross_set.UpdateCrossSetsForMove(replay, m, lex.gd, lex.ld)
+		} else {
+			ply.Pass = true
+		}
+		variation = append(variation, ply)
+	}
+
+	response := SolveEndgameResponse{
+		Variation:   variation,
+		FinalSpread: req.Score - req.OppScore + value,
+		Completed:   completed,
+		Depth:       depth,
+		Nodes:       solver.nodes,
+		Lexicon:     lex.name,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// endgameSolver runs an iteratively deepened negamax search with alpha-beta
+// pruning. Each ply has its own board and generator so that moves can be
+// tried and undone by copying the parent ply's board.
+type endgameSolver struct {
+	lex        *lexicon
+	boards     []*board.GameBoard
+	generators []*movegen.GordonGenerator
+	racks      [2]*tilemapping.Rack
+
+	deadline time.Time
+	timedOut bool
+	horizon  bool // some line was cut off before the game ended
+	nodes    int
+}
+
+func newEndgameSolver(lex *lexicon, bd *board.GameBoard, maxPlies int, rack, oppRack *tilemapping.Rack) *endgameSolver {
+	s := &endgameSolver{lex: lex, racks: [2]*tilemapping.Rack{rack, oppRack}}
+	for i := 0; i <= maxPlies; i++ {
+		b := bd.Copy()
+		gen := movegen.NewGordonGenerator(lex.gd, b, lex.ld)
+		gen.SetGenPass(true)
+		s.boards = append(s.boards, b)
+		s.generators = append(s.generators, gen)
+	}
+	return s
+}
+
+// solve searches one ply deeper at a time until the game tree is exhausted,
+// the maximum depth is reached, or time runs out. It returns the value of
+// the best line for the player to move, the line itself, the depth of the
+// last finished iteration, and whether that result is exact.
+func (s *endgameSolver) solve(limit time.Duration) (int, []*move.Move, int, bool) {
+	s.deadline = time.Now().Add(limit)
+	bestValue, depth := 0, 0
+	var bestPV []*move.Move
+	for d := 1; d < len(s.boards); d++ {
+		s.horizon = false
+		value, pv := s.negamax(0, d, -math.MaxInt32, math.MaxInt32, false)
+		if s.timedOut {
+			break
+		}
+		bestValue, bestPV, depth = value, pv, d
+		if !s.horizon {
+			return bestValue, bestPV, depth, true
+		}
+	}
+	return bestValue, bestPV, depth, false
+}
+
+// negamax returns the best achievable point difference, from the point of
+// view of the player to move at ply, over the rest of the game, and the
+// moves that achieve it. Player 0 moves on even plies.
+func (s *endgameSolver) negamax(ply, remaining, alpha, beta int, lastWasPass bool) (int, []*move.Move) {
+	s.nodes++
+	if s.nodes%1000 == 0 && time.Now().After(s.deadline) {
+		s.timedOut = true
+	}
+	if s.timedOut {
+		return 0, nil
+	}
+
+	mover := s.racks[ply%2]
+	other := s.racks[1-ply%2]
+	moves := append([]*move.Move(nil), s.generators[ply].GenAll(mover, false)...)
+	orderEndgameMoves(moves)
+
+	best := -math.MaxInt32
+	var bestPV []*move.Move
+	saved := mover.TilesOn()
+	for _, m := range moves {
+		var value int
+		var pv []*move.Move
+		switch {
+		case m.Action() != move.MoveTypePlay && lastWasPass:
+			// Two passes in a row end the game; each side loses the
+			// value of the tiles still on their rack.
+			value = other.ScoreOn(s.lex.ld) - mover.ScoreOn(s.lex.ld)
+		case m.Action() != move.MoveTypePlay:
+			if remaining == 1 {
+				s.horizon = true
+				value = 0
+				break
+			}
+			// The reply is made on this ply's board, not on whatever
+			// play was last tried here.
+			s.boards[ply+1].CopyFrom(s.boards[ply])
+			value, pv = s.negamax(ply+1, remaining-1, -beta, -alpha, true)
+			value = -value
+		case len(m.Leave()) == 0:
+			// Going out ends the game and earns twice the opponent's
+			// remaining tiles.
+			value = m.Score() + 2*other.ScoreOn(s.lex.ld)
+		case remaining == 1:
+			s.horizon = true
+			value = m.Score()
+		default:
+			next := s.boards[ply+1]
+			next.CopyFrom(s.boards[ply])
+			next.PlayMove(m)
+			cross_set.UpdateCrossSetsForMove(next, m, s.lex.gd, s.lex.ld)
+			mover.Set(m.Leave())
+			value, pv = s.negamax(ply+1, remaining-1, m.Score()-beta, m.Score()-alpha, false)
+			value = m.Score() - value
+			mover.Set(saved)
+		}
+		if s.timedOut {
+			return 0, nil
+		}
+
+		if value > best {
+			best = value
+			bestPV = append([]*move.Move{m}, pv...)
+		}
+		if best > alpha {
+			alpha = best
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best, bestPV
+}
+
+// orderEndgameMoves puts plays that go out first, then the rest by score,
+// so that alpha-beta finds strong lines early. Passes go last.
+func orderEndgameMoves(moves []*move.Move) {
+	rank := func(m *move.Move) int {
+		switch {
+		case m.Action() != move.MoveTypePlay:
+			return math.MinInt32
+		case len(m.Leave()) == 0:
+			return 1000 + m.Score()
+		default:
+			return m.Score()
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return rank(moves[i]) > rank(moves[j])
+	})
+}
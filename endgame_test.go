@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/domino14/macondo/config"
+	"github.com/domino14/macondo/move"
+	"github.com/domino14/word-golib/kwg"
+	"github.com/domino14/word-golib/tilemapping"
+)
+
+// testLexicon builds a KWG holding just words, saves it under a temporary
+// data path, and loads it with the English letter distribution.
+func testLexicon(t *testing.T, name string, words ...string) *lexicon {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "lexica", "gaddag"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "lexica", "gaddag", name+".kwg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := binary.Write(f, binary.LittleEndian, buildKWG(words)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(wd, "letterdistributions"), filepath.Join(dir, "letterdistributions")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Set("data-path", dir)
+	gd, err := kwg.GetKWG(cfg.WGLConfig(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ld, err := tilemapping.ProbableLetterDistribution(cfg.WGLConfig(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &lexicon{name: name, gd: gd, alph: gd.GetAlphabet(), ld: ld}
+}
+
+// kwgNode is a node of the tries buildKWG writes out.
+type kwgNode struct {
+	children map[byte]*kwgNode
+	accepts  bool
+}
+
+func (n *kwgNode) add(word []byte) {
+	for i, c := range word {
+		child, ok := n.children[c]
+		if !ok {
+			child = &kwgNode{children: map[byte]*kwgNode{}}
+			n.children[c] = child
+		}
+		if i == len(word)-1 {
+			child.accepts = true
+		}
+		n = child
+	}
+}
+
+// buildKWG returns the nodes of a KWG for words, which must be uppercase
+// A-Z: a DAWG (left as a trie) at node 0 and a GADDAG at node 1.
+func buildKWG(words []string) []uint32 {
+	dawg := &kwgNode{children: map[byte]*kwgNode{}}
+	gaddag := &kwgNode{children: map[byte]*kwgNode{}}
+	for _, w := range words {
+		mw := make([]byte, len(w))
+		for i := range w {
+			mw[i] = w[i] - 'A' + 1
+		}
+		dawg.add(mw)
+		for i := 1; i <= len(mw); i++ {
+			var path []byte
+			for j := i - 1; j >= 0; j-- {
+				path = append(path, mw[j])
+			}
+			if i < len(mw) {
+				path = append(append(path, 0), mw[i:]...)
+			}
+			gaddag.add(path)
+		}
+	}
+
+	nodes := []uint32{0, 0x400000}
+	var write func(n *kwgNode) uint32
+	write = func(n *kwgNode) uint32 {
+		if len(n.children) == 0 {
+			return 0
+		}
+		var keys []byte
+		for k := range n.children {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		start := uint32(len(nodes))
+		nodes = append(nodes, make([]uint32, len(keys))...)
+		for i, k := range keys {
+			child := n.children[k]
+			v := uint32(k)<<24 | write(child)
+			if child.accepts {
+				v |= 0x800000
+			}
+			if i == len(keys)-1 {
+				v |= 0x400000
+			}
+			nodes[int(start)+i] = v
+		}
+		return start
+	}
+	d := write(dawg)
+	nodes[0] |= d
+	g := write(gaddag)
+	nodes[1] |= g
+	return nodes
+}
+
+// TestEndgamePassThenReply checks a position where the only play, ABS,
+// lets the opponent go out with ZABS, so the best line is to pass and let
+// the opponent, who then has no play, pass back.
+func TestEndgamePassThenReply(t *testing.T) {
+	lex := testLexicon(t, "NWL_ENDGAME_TEST", "AB", "ABS", "ZABS")
+	cells := make([][]BoardCell, boardSize)
+	for i := range cells {
+		cells[i] = make([]BoardCell, boardSize)
+	}
+	cells[7][7] = BoardCell{Letter: "A"}
+	cells[7][8] = BoardCell{Letter: "B"}
+	bd, err := loadBoard(cells, lex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solver := newEndgameSolver(lex, bd, 4,
+		tilemapping.RackFromString("SQ", lex.alph),
+		tilemapping.RackFromString("Z", lex.alph))
+	value, pv, _, completed := solver.solve(10 * time.Second)
+	if !completed {
+		t.Fatal("search did not complete")
+	}
+	// After two passes each side loses what is left on its rack:
+	// SQ is 11 points and Z is 10.
+	if value != -1 {
+		t.Errorf("value = %d, want -1", value)
+	}
+	if len(pv) != 2 || pv[0].Action() != move.MoveTypePass || pv[1].Action() != move.MoveTypePass {
+		t.Errorf("variation = %v, want a pass answered by a pass", pv)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/domino14/word-golib/tilemapping"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/move"
+)
+
+// rackSize is the number of tiles a full rack holds.
+const rackSize = 7
+
+// defaultBagSize estimates how many tiles are left in the bag when the caller
+// doesn't say: the full distribution, less the tiles already on the board,
+// our rack, and a full opponent rack.
+func defaultBagSize(ld *tilemapping.LetterDistribution, tilesOnBoard, ourRack int) int {
+	n := int(ld.NumTotalLetters()) - tilesOnBoard - ourRack - rackSize
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// bagWithTiles returns a bag of lex's distribution holding n tiles. The
+// equity calculators only look at how many tiles remain, not which.
+func bagWithTiles(lex *lexicon, n int) *tilemapping.Bag {
+	bag := tilemapping.NewBag(lex.ld, lex.alph)
+	drop := bag.TilesRemaining() - n
+	if drop > 0 {
+		bag.Draw(drop, make([]tilemapping.MachineLetter, drop))
+	}
+	return bag
+}
+
+// setEquities sets the equity of every move from lex's leave table: score
+// plus leave value while tiles remain, with macondo's pre-endgame and
+// endgame adjustments as the bag runs out.
+func setEquities(moves []*move.Move, bd *board.GameBoard, lex *lexicon, bag *tilemapping.Bag) {
+	for _, m := range moves {
+		m.SetEquity(lex.calc.Equity(m, bd, bag, nil))
+	}
+}
+
+// sortByEquity orders moves best equity first, keeping the generator's
+// order among equal equities.
+func sortByEquity(moves []*move.Move) {
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].Equity() > moves[j].Equity()
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/domino14/word-golib/tilemapping"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/move"
+)
+
+// MoveFilter narrows /generate-moves to the plays a caller asks about. All
+// the criteria given must hold. Word lengths count letters played through,
+// CoverSquare is met by a play whose word spans the square (so "through the
+// Z" is the Z's square), UseTiles lists rack tiles the play must place
+// ("?" for a blank), and WordPattern is a regular expression matched
+// against Word. Every criterion but MinScore describes a placement, so
+// setting any of them leaves out exchanges and passes.
+type MoveFilter struct {
+	MinTilesPlayed int    `json:"minTilesPlayed,omitempty"`
+	BingosOnly     bool   `json:"bingosOnly,omitempty"`
+	UseTiles       string `json:"useTiles,omitempty"`
+	CoverSquare    string `json:"coverSquare,omitempty"` // e.g. "H8" or "8H"
+	Direction      string `json:"direction,omitempty"`   // "across" or "down"
+	MinLength      int    `json:"minLength,omitempty"`
+	MaxLength      int    `json:"maxLength,omitempty"`
+	MinScore       int    `json:"minScore,omitempty"`
+	WordPattern    string `json:"wordPattern,omitempty"`
+}
+
+// moveFilter is a MoveFilter checked and ready to match moves.
+type moveFilter struct {
+	MoveFilter
+	placement  bool
+	useTiles   map[tilemapping.MachineLetter]int
+	coverRow   int
+	coverCol   int
+	wordRegexp *regexp.Regexp
+}
+
+// compile checks f and prepares it for matching moves in lex.
+func (f *MoveFilter) compile(lex *lexicon) (*moveFilter, error) {
+	mf := &moveFilter{MoveFilter: *f, coverRow: -1}
+	if f.MinTilesPlayed < 0 || f.MinLength < 0 || f.MaxLength < 0 {
+		return nil, errors.New("filter minimums and maximums cannot be negative")
+	}
+	if f.MaxLength > 0 && f.MaxLength < f.MinLength {
+		return nil, errors.New("filter maxLength cannot be less than minLength")
+	}
+	if f.Direction != "" && f.Direction != "across" && f.Direction != "down" {
+		return nil, errors.New("filter direction must be \"across\" or \"down\"")
+	}
+	if f.UseTiles != "" {
+		tiles, err := tilemapping.ToMachineLetters(strings.ToUpper(strings.ReplaceAll(f.UseTiles, " ", "")), lex.alph)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter useTiles %q", f.UseTiles)
+		}
+		mf.useTiles = map[tilemapping.MachineLetter]int{}
+		for _, ml := range tiles {
+			mf.useTiles[ml]++
+		}
+	}
+	if f.CoverSquare != "" {
+		row, col, err := parseSquare(f.CoverSquare)
+		if err != nil {
+			return nil, fmt.Errorf("filter coverSquare: %v", err)
+		}
+		mf.coverRow, mf.coverCol = row, col
+	}
+	if f.WordPattern != "" {
+		re, err := regexp.Compile(f.WordPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter wordPattern: %v", err)
+		}
+		mf.wordRegexp = re
+	}
+	mf.placement = f.MinTilesPlayed > 0 || f.BingosOnly || f.UseTiles != "" || f.CoverSquare != "" ||
+		f.Direction != "" || f.MinLength > 0 || f.MaxLength > 0 || f.WordPattern != ""
+	return mf, nil
+}
+
+// match reports whether m, a move on bd, meets every criterion.
+func (f *moveFilter) match(m *move.Move, bd *board.GameBoard, lex *lexicon) bool {
+	if m.Score() < f.MinScore {
+		return false
+	}
+	if m.Action() != move.MoveTypePlay {
+		return !f.placement
+	}
+	if m.TilesPlayed() < f.MinTilesPlayed || (f.BingosOnly && m.TilesPlayed() != rackSize) {
+		return false
+	}
+
+	row, col, vertical := m.CoordsAndVertical()
+	if (f.Direction == "across" && vertical) || (f.Direction == "down" && !vertical) {
+		return false
+	}
+	tiles := m.Tiles()
+	if len(tiles) < f.MinLength || (f.MaxLength > 0 && len(tiles) > f.MaxLength) {
+		return false
+	}
+	if f.coverRow >= 0 {
+		along, across := f.coverCol-col, f.coverRow-row
+		if vertical {
+			along, across = across, along
+		}
+		if across != 0 || along < 0 || along >= len(tiles) {
+			return false
+		}
+	}
+
+	if f.useTiles != nil {
+		placed := map[tilemapping.MachineLetter]int{}
+		for _, ml := range tiles {
+			if ml == 0 {
+				continue
+			}
+			if ml.IsBlanked() {
+				ml = 0
+			}
+			placed[ml]++
+		}
+		for ml, n := range f.useTiles {
+			if placed[ml] < n {
+				return false
+			}
+		}
+	}
+	if f.wordRegexp != nil {
+		if word, _ := playWord(m, bd, lex); !f.wordRegexp.MatchString(word) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/domino14/word-golib/kwg"
+	"github.com/domino14/word-golib/tilemapping"
+)
+
+// maxHookWords caps how many words one /hooks request may ask about.
+const maxHookWords = 1000
+
+// HooksRequest is the body of /hooks: a single Word, a list of Words, or
+// both.
+type HooksRequest struct {
+	Word              string   `json:"word,omitempty"`
+	Words             []string `json:"words,omitempty"`
+	IncludeInnerHooks bool     `json:"includeInnerHooks,omitempty"`
+	Lexicon           string   `json:"lexicon,omitempty"`
+}
+
+// WordHooks lists the letters that make a new word when added to the front
+// or back of a word. InnerHooks, when asked for, says whether the word
+// stays a word with its first or last letter taken off. A word not in the
+// lexicon has no hooks.
+type WordHooks struct {
+	FrontHooks []string    `json:"frontHooks"`
+	BackHooks  []string    `json:"backHooks"`
+	InnerHooks *InnerHooks `json:"innerHooks,omitempty"`
+}
+
+type InnerHooks struct {
+	Front bool `json:"front"` // the word without its first letter is a word
+	Back  bool `json:"back"`  // the word without its last letter is a word
+}
+
+type HookResult struct {
+	Word    string `json:"word"`
+	IsValid bool   `json:"isValid"`
+	WordHooks
+}
+
+type HooksResponse struct {
+	Words   []HookResult `json:"words"`
+	Count   int          `json:"count"`
+	Lexicon string       `json:"lexicon"`
+}
+
+func hooksHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HooksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	words := req.Words
+	if req.Word != "" {
+		words = append([]string{req.Word}, words...)
+	}
+	if len(words) == 0 {
+		http.Error(w, "Word or Words is required", http.StatusBadRequest)
+		return
+	}
+	if len(words) > maxHookWords {
+		http.Error(w, "too many words", http.StatusBadRequest)
+		return
+	}
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := HooksResponse{Words: make([]HookResult, 0, len(words)), Lexicon: lex.name}
+	for _, word := range words {
+		word = strings.ToUpper(strings.TrimSpace(word))
+		response.Words = append(response.Words, HookResult{
+			Word:      word,
+			IsValid:   isValidWord(lex.gd, word),
+			WordHooks: findHooks(lex, word, req.IncludeInnerHooks),
+		})
+	}
+	response.Count = len(response.Words)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// findHooks looks up the hooks of word in lex. Front hooks are read from
+// the GADDAG half of the KWG and back hooks from the DAWG half.
+func findHooks(lex *lexicon, word string, inner bool) WordHooks {
+	hooks := WordHooks{FrontHooks: []string{}, BackHooks: []string{}}
+	mw, ok := toWord(lex.gd, word)
+	if inner {
+		hooks.InnerHooks = &InnerHooks{}
+	}
+	if !ok || !kwg.FindMachineWord(lex.gd, mw) {
+		return hooks
+	}
+
+	letters := func(mls []tilemapping.MachineLetter) []string {
+		s := []string{}
+		for _, ml := range mls {
+			s = append(s, lex.alph.Letter(ml))
+		}
+		return s
+	}
+	hooks.FrontHooks = letters(kwg.FindHooks(lex.gd, mw, kwg.FrontHooks))
+	hooks.BackHooks = letters(kwg.FindHooks(lex.gd, mw, kwg.BackHooks))
+	if inner && len(mw) > 2 {
+		hooks.InnerHooks.Front = kwg.FindInnerHook(lex.gd, mw, kwg.FrontInnerHook)
+		hooks.InnerHooks.Back = kwg.FindInnerHook(lex.gd, mw, kwg.BackInnerHook)
+	}
+	return hooks
+}
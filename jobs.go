@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jobTTL is how long a finished job's result is kept.
+	jobTTL = 30 * time.Minute
+	// jobEvictInterval is how often expired jobs are looked for.
+	jobEvictInterval = time.Minute
+	// maxRunningJobs is how many jobs may run at once. Each one can use
+	// every CPU, so more would only queue up behind each other.
+	maxRunningJobs = 4
+)
+
+// Job statuses.
+const (
+	jobRunning   = "running"
+	jobCompleted = "completed"
+	jobFailed    = "failed"
+	jobCancelled = "cancelled"
+)
+
+// CreateJobRequest starts a job of the given Type, whose Request is the body
+// the synchronous endpoint of that name takes.
+type CreateJobRequest struct {
+	Type    string          `json:"type"` // "bulk-move-gen"
+	Request json.RawMessage `json:"request"`
+}
+
+// JobResponse reports a job's state. Partial holds the results so far while
+// the job runs; Result holds the final results once it has completed.
+type JobResponse struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"` // "running", "completed", "failed", or "cancelled"
+	Done       int        `json:"done"`
+	Total      int        `json:"total"`
+	Percent    float64    `json:"percent"`
+	Partial    any        `json:"partial,omitempty"`
+	Result     any        `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// job is one asynchronous run. Its runner updates done and whatever partial
+// reads while holding mu.
+type job struct {
+	id      string
+	kind    string
+	total   int
+	created time.Time
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	status   string
+	done     int
+	partial  func() any
+	result   any
+	err      string
+	finished time.Time
+}
+
+func newJob(kind string, total int, cancel context.CancelFunc) *job {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &job{
+		id:      hex.EncodeToString(id),
+		kind:    kind,
+		total:   total,
+		created: time.Now(),
+		cancel:  cancel,
+		status:  jobRunning,
+	}
+}
+
+// finish records the outcome of the job's run. A job that was cancelled
+// stays cancelled whatever its run returned.
+func (j *job) finish(result any, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != jobRunning {
+		return
+	}
+	j.finished = time.Now()
+	switch {
+	case errors.Is(err, context.Canceled):
+		j.status = jobCancelled
+	case err != nil:
+		j.status = jobFailed
+		j.err = err.Error()
+	default:
+		j.status = jobCompleted
+		j.result = result
+	}
+	j.cancel()
+}
+
+// stop cancels the job if it is still running.
+func (j *job) stop() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status == jobRunning {
+		j.status = jobCancelled
+		j.finished = time.Now()
+		j.cancel()
+	}
+}
+
+func (j *job) snapshot() JobResponse {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	resp := JobResponse{
+		ID:        j.id,
+		Type:      j.kind,
+		Status:    j.status,
+		Done:      j.done,
+		Total:     j.total,
+		Result:    j.result,
+		Error:     j.err,
+		CreatedAt: j.created,
+	}
+	if j.total > 0 {
+		resp.Percent = float64(j.done) / float64(j.total) * 100.0
+	}
+	if j.status == jobRunning && j.partial != nil {
+		resp.Partial = j.partial()
+	}
+	if !j.finished.IsZero() {
+		finished := j.finished
+		resp.FinishedAt = &finished
+	}
+	return resp
+}
+
+// jobStore holds jobs in memory until jobTTL after they finish. It also
+// hands out the slots that limit how many jobs run at once.
+type jobStore struct {
+	mu    sync.Mutex
+	jobs  map[string]*job
+	slots chan struct{}
+}
+
+var jobs = &jobStore{jobs: map[string]*job{}, slots: make(chan struct{}, maxRunningJobs)}
+
+// acquire takes a running-job slot, or returns false if all are taken.
+func (s *jobStore) acquire() bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release gives back a slot taken by acquire.
+func (s *jobStore) release() {
+	<-s.slots
+}
+
+func (s *jobStore) add(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[j.id] = j
+}
+
+func (s *jobStore) get(id string) *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id]
+}
+
+// evict drops the jobs that finished more than jobTTL before now.
+func (s *jobStore) evict(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, j := range s.jobs {
+		j.mu.Lock()
+		expired := !j.finished.IsZero() && now.Sub(j.finished) > jobTTL
+		j.mu.Unlock()
+		if expired {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// evictLoop runs evict every jobEvictInterval, forever.
+func (s *jobStore) evictLoop() {
+	for now := range time.Tick(jobEvictInterval) {
+		s.evict(now)
+	}
+}
+
+// jobStarters maps each job type to the function that checks its request
+// and starts it running. The caller holds a running-job slot for the job;
+// a starter that returns a job must release it once the job's work has
+// stopped.
+var jobStarters = map[string]func(json.RawMessage) (*job, error){
+	"bulk-move-gen": startBulkJob,
+}
+
+func jobTypes() []string {
+	types := make([]string, 0, len(jobStarters))
+	for t := range jobStarters {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// startBulkJob runs a /bulk-move-gen request in the background. Partial
+// results are the statistics over the iterations finished so far.
+func startBulkJob(raw json.RawMessage) (*job, error) {
+	var req BulkMoveGenRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, errors.New("Invalid JSON")
+	}
+	if _, err := applyCGP(req.CGP, &req.Board, &req.Rack, &req.Lexicon); err != nil {
+		return nil, err
+	}
+	run, err := newBulkRun(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := newJob("bulk-move-gen", run.iterations, cancel)
+	stats := newBulkStats()
+	j.partial = func() any { return run.response(stats) }
+	go func() {
+		defer jobs.release()
+		resp, err := run.run(ctx, func(sample bulkSample) {
+			j.mu.Lock()
+			stats.add(sample)
+			j.done++
+			j.mu.Unlock()
+		})
+		j.finish(resp, err)
+	}()
+	return j, nil
+}
+
+// createJobHandler starts a job and returns its ID and initial state
+// straight away; poll /jobs/{id} for progress and the result.
+func createJobHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	start, ok := jobStarters[req.Type]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown job type %q (available: %s)", req.Type, strings.Join(jobTypes(), ", ")),
+			http.StatusBadRequest)
+		return
+	}
+	if !jobs.acquire() {
+		http.Error(w, fmt.Sprintf("too many jobs running (at most %d); try again later", maxRunningJobs),
+			http.StatusTooManyRequests)
+		return
+	}
+	j, err := start(req.Request)
+	if err != nil {
+		jobs.release()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	jobs.add(j)
+	fmt.Printf("Started %s job %s\n", j.kind, j.id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+j.id)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(j.snapshot())
+}
+
+// jobHandler reports on a job (GET) or cancels it (DELETE).
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	j := jobs.get(r.PathValue("id"))
+	if j == nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		j.stop()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(j.snapshot())
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/domino14/macondo/config"
+	"github.com/domino14/macondo/equity"
+	"github.com/domino14/word-golib/kwg"
+	"github.com/domino14/word-golib/tilemapping"
+)
+
+// defaultLexiconName is the lexicon used when a request does not name one.
+const defaultLexiconName = "NWL23"
+
+// lexicon bundles a loaded word graph with the alphabet, letter
+// distribution, and leave-value table that go with it.
+type lexicon struct {
+	name string
+	gd   *kwg.KWG
+	alph *tilemapping.TileMapping
+	ld   *tilemapping.LetterDistribution
+	calc *equity.CombinedStaticCalculator
+
+	ranks probabilityRanks
+}
+
+// The registry is filled once by loadLexica at startup and only read
+// afterwards, so handlers may share it without locking.
+var (
+	lexica         = map[string]*lexicon{}
+	defaultLexicon *lexicon
+)
+
+// loadLexica loads every KWG found under <data-path>/lexica/gaddag, along
+// with the letter distribution each one is normally played with and its
+// leave values from <data-path>/strategy/<lexicon>/leaves.klv2. A missing
+// leave file is not fatal; macondo falls back to zero-valued leaves.
+func loadLexica(cfg *config.Config) error {
+	wglCfg := cfg.WGLConfig()
+	paths, err := filepath.Glob(filepath.Join(wglCfg.DataPath, "lexica", "gaddag", "*.kwg"))
+	if err != nil {
+		return fmt.Errorf("failed to list lexica: %v", err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".kwg")
+		gd, err := kwg.GetKWG(wglCfg, name)
+		if err != nil {
+			return fmt.Errorf("failed to load lexicon %s: %v", name, err)
+		}
+		ld, err := tilemapping.ProbableLetterDistribution(wglCfg, name)
+		if err != nil {
+			return fmt.Errorf("failed to load letter distribution for %s: %v", name, err)
+		}
+		calc, err := equity.NewCombinedStaticCalculator(name, cfg, "", "")
+		if err != nil {
+			return fmt.Errorf("failed to load leave values for %s: %v", name, err)
+		}
+		lexica[strings.ToUpper(name)] = &lexicon{
+			name: name,
+			gd:   gd,
+			alph: gd.GetAlphabet(),
+			ld:   ld,
+			calc: calc,
+		}
+	}
+	if len(lexica) == 0 {
+		return fmt.Errorf("no lexica found in %s", filepath.Join(wglCfg.DataPath, "lexica", "gaddag"))
+	}
+
+	defaultLexicon = lexica[defaultLexiconName]
+	if defaultLexicon == nil {
+		defaultLexicon = lexica[strings.ToUpper(lexiconNames()[0])]
+	}
+	return nil
+}
+
+// lexiconNames returns the names of all loaded lexica in sorted order.
+func lexiconNames() []string {
+	names := make([]string, 0, len(lexica))
+	for _, lex := range lexica {
+		names = append(names, lex.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getLexicon returns the lexicon with the given name, or the default lexicon
+// if name is empty. Names are matched case-insensitively.
+func getLexicon(name string) (*lexicon, error) {
+	if name == "" {
+		return defaultLexicon, nil
+	}
+	lex, ok := lexica[strings.ToUpper(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown lexicon %q (available: %s)", name, strings.Join(lexiconNames(), ", "))
+	}
+	return lex, nil
+}
+
+// maxWordLength is the longest word that fits on a 15x15 board.
+const maxWordLength = 15
+
+// toWord converts a user-supplied word into machine letters for lookup in
+// g. It returns false if the word is empty, too long, or contains anything
+// other than plain letters of the lexicon's alphabet.
+func toWord(g *kwg.KWG, word string) (tilemapping.MachineWord, bool) {
+	word = strings.ToUpper(strings.TrimSpace(word))
+	if word == "" {
+		return nil, false
+	}
+	mw, err := tilemapping.ToMachineWord(word, g.GetAlphabet())
+	if err != nil || len(mw) > maxWordLength {
+		return nil, false
+	}
+	for _, ml := range mw {
+		// 0 is the blank (or a played-through marker); neither is a letter.
+		if ml == 0 {
+			return nil, false
+		}
+	}
+	return mw, true
+}
+
+// isValidWord reports whether word is in the lexicon g. The lookup walks the
+// KWG's DAWG directly, so it does not depend on what a rack could place.
+func isValidWord(g *kwg.KWG, word string) bool {
+	mw, ok := toWord(g, word)
+	if !ok {
+		return false
+	}
+	return kwg.FindMachineWord(g, mw)
+}
@@ -7,16 +7,14 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
-	"sort"
 	"strings"
-	"time"
 
-	"github.com/domino14/word-golib/kwg"
 	"github.com/domino14/word-golib/tilemapping"
+	"github.com/rs/zerolog"
 
 	"github.com/domino14/macondo/board"
 	"github.com/domino14/macondo/config"
-	"github.com/domino14/macondo/cross_set"
+	"github.com/domino14/macondo/move"
 	"github.com/domino14/macondo/movegen"
 )
 
@@ -29,31 +27,72 @@ func setCORSHeaders(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Vary", "Origin")
 	}
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 }
 
 type GenerateMovesRequest struct {
-	Rack  string     `json:"rack"`
-	Board [][]string `json:"board"` // 15x15 board as strings
-	TopN  int        `json:"topN,omitempty"`
+	Rack    string        `json:"rack"`
+	Board   [][]BoardCell `json:"board"` // 15x15 board; lowercase letters are blanks
+	TopN    int           `json:"topN,omitempty"`
+	Lexicon string        `json:"lexicon,omitempty"` // defaults to NWL23
+	Sort    string        `json:"sort,omitempty"`    // "score" (default) or "equity"
+	BagSize *int          `json:"bagSize,omitempty"` // tiles left in the bag; estimated from the board if omitted
+	CGP     string        `json:"cgp,omitempty"`     // Position in CGP notation; replaces board
+	// IncludeExchanges adds a pass and, when the bag holds at least a full
+	// rack, every exchange.
+	IncludeExchanges bool        `json:"includeExchanges,omitempty"`
+	Filter           *MoveFilter `json:"filter,omitempty"` // applied before TopN; Total counts the moves that pass
+	// Offset and Limit page through the sorted moves (see sortMoves); Limit
+	// defaults to TopN. Cursor, taken from a previous response's
+	// NextCursor for the same request, replaces Offset.
+	Offset int    `json:"offset,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
 }
 
+// Move is a single generated play. Row and Col are 0-indexed and point at the
+// first square of the word; Word includes any letters played through, and
+// FromRack marks, per letter of Word, whether that tile came from the rack.
+// Blanks lists the indexes into Word that are blank tiles. Equity is the
+// score plus LeaveValue (the leave table's value for Leave, which only counts
+// while tiles remain in the bag) and any pre-endgame or endgame adjustment.
+// Words breaks Score down into the words the play forms; their scores plus
+// BingoBonus make up Score. For an exchange or pass, Action says which,
+// Exchanged lists the tiles thrown back, and the fields describing a
+// placement are left empty.
 type Move struct {
-	Position string `json:"position"`
-	Word     string `json:"word"`
-	Score    int    `json:"score"`
-	Leave    string `json:"leave"`
+	Action      string       `json:"action"` // "play", "exchange", or "pass"
+	Exchanged   string       `json:"exchanged,omitempty"`
+	Position    string       `json:"position"`
+	Row         int          `json:"row"`
+	Col         int          `json:"col"`
+	Direction   string       `json:"direction"` // "across" or "down"
+	Word        string       `json:"word"`
+	FromRack    []bool       `json:"fromRack"`
+	Blanks      []int        `json:"blanks"`
+	TilesPlayed int          `json:"tilesPlayed"`
+	Score       int          `json:"score"`
+	Leave       string       `json:"leave"`
+	LeaveValue  float64      `json:"leaveValue"`
+	Equity      float64      `json:"equity"`
+	Words       []ScoredWord `json:"words,omitempty"` // main word first, then hooked cross-words
+	BingoBonus  int          `json:"bingoBonus"`
 }
 
 type GenerateMovesResponse struct {
-	Moves []Move `json:"moves"`
-	Total int    `json:"total"`
+	Moves      []Move `json:"moves"`
+	Total      int    `json:"total"`
+	Offset     int    `json:"offset"`               // index of Moves[0] in the full sorted list
+	NextCursor string `json:"nextCursor,omitempty"` // fetches the next page; omitted on the last
+	Lexicon    string `json:"lexicon"`
+	CGP        string `json:"cgp"` // The position the moves were generated for
 }
 
 type ValidateWordRequest struct {
-	Word string `json:"word"`
+	Word    string `json:"word"`
+	Lexicon string `json:"lexicon,omitempty"`
 }
 
 type ValidateWordResponse struct {
@@ -62,50 +101,53 @@ type ValidateWordResponse struct {
 	Lexicon   string `json:"lexicon"`
 }
 
-type SubanagramSearchRequest struct {
-	Letters string `json:"letters"`
-}
-
-type SubanagramSearchResponse struct {
-	Letters      string   `json:"letters"`
-	Subanagrams  []string `json:"subanagrams"`
-	Count        int      `json:"count"`
-	Lexicon      string   `json:"lexicon"`
-}
-
-type AnagramSearchRequest struct {
-	Letters string `json:"letters"`
-}
-
-type AnagramSearchResponse struct {
-	Letters   string   `json:"letters"`
-	Anagrams  []string `json:"anagrams"`
-	Count     int      `json:"count"`
-	Lexicon   string   `json:"lexicon"`
-}
-
 type BulkMoveGenRequest struct {
-	Board      [][]string `json:"board"`      // 15x15 board as strings
-	TilePool   string     `json:"tilePool"`   // String representation of available tiles (e.g., "AABCDEFGHIJKLMNOPQRSTUVWXYZ")
-	Iterations int        `json:"iterations,omitempty"` // Number of iterations (default 1000)
+	Board      [][]BoardCell `json:"board"`                // 15x15 board; lowercase letters are blanks
+	TilePool   string        `json:"tilePool"`             // String representation of available tiles (e.g., "AABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	Iterations int           `json:"iterations,omitempty"` // Number of iterations (default 1000, at most 100000)
+	Lexicon    string        `json:"lexicon,omitempty"`
+	// DeriveTilePool replaces TilePool with the distribution minus the
+	// board minus Rack.
+	DeriveTilePool bool   `json:"deriveTilePool,omitempty"`
+	Rack           string `json:"rack,omitempty"`
+	CGP            string `json:"cgp,omitempty"`           // Position in CGP notation; replaces board
+	Workers        int    `json:"workers,omitempty"`       // Goroutines to run iterations on (default one per CPU)
+	Seed           *int64 `json:"seed,omitempty"`          // Seeds the rack draws; random if omitted
+	ProgressEvery  int    `json:"progressEvery,omitempty"` // Iterations between /bulk-move-gen/stream progress events (default 100)
 }
 
+// BulkMoveGenResponse summarizes the highest-scoring play of each random
+// rack. Iterations is the number requested; the statistics are over the
+// EvaluatedIterations for which the pool could fill a rack, and a rack with
+// no legal play counts as scoring zero.
 type BulkMoveGenResponse struct {
-	Iterations     int     `json:"iterations"`
-	AverageScore   float64 `json:"averageScore"`
-	BingoPercent   float64 `json:"bingoPercent"`
-	TotalBingos    int     `json:"totalBingos"`
-	TotalScore     int     `json:"totalScore"`
-	Lexicon        string  `json:"lexicon"`
+	Iterations          int              `json:"iterations"`
+	Seed                int64            `json:"seed"` // pass back to reproduce this run
+	EvaluatedIterations int              `json:"evaluatedIterations"`
+	AverageScore        float64          `json:"averageScore"`
+	BingoPercent        float64          `json:"bingoPercent"`
+	TotalBingos         int              `json:"totalBingos"`
+	TotalScore          int              `json:"totalScore"`
+	NoPlayPercent       float64          `json:"noPlayPercent"`
+	ScorePercentiles    ScorePercentiles `json:"scorePercentiles"`
+	ScoreHistogram      []ScoreBucket    `json:"scoreHistogram"`
+	TopPlays            []PlayFrequency  `json:"topPlays"` // most frequent top plays, most frequent first
+	Lexicon             string           `json:"lexicon"`
 }
 
 type ValidateWordsRequest struct {
-	Words []string `json:"words"`
+	Words   []string `json:"words"`
+	Lexicon string   `json:"lexicon,omitempty"`
+	// IncludeHooks adds each word's front and back hooks, and its inner
+	// hooks too if IncludeInnerHooks is set.
+	IncludeHooks      bool `json:"includeHooks,omitempty"`
+	IncludeInnerHooks bool `json:"includeInnerHooks,omitempty"`
 }
 
 type WordValidation struct {
-	Word    string `json:"word"`
-	IsValid bool   `json:"isValid"`
+	Word    string     `json:"word"`
+	IsValid bool       `json:"isValid"`
+	Hooks   *WordHooks `json:"hooks,omitempty"`
 }
 
 type ValidateWordsResponse struct {
@@ -116,13 +158,6 @@ type ValidateWordsResponse struct {
 	Lexicon  string           `json:"lexicon"`
 }
 
-// Global state (safe for demo, not for production concurrency)
-var (
-	gd   *kwg.KWG
-	alph *tilemapping.TileMapping
-	ld   *tilemapping.LetterDistribution
-)
-
 func main() {
 	if err := initService(); err != nil {
 		log.Fatalf("Failed to initialize service: %v", err)
@@ -135,6 +170,17 @@ func main() {
 	http.HandleFunc("/find-subanagrams", findSubanagramsHandler)
 	http.HandleFunc("/find-anagrams", findAnagramsHandler)
 	http.HandleFunc("/bulk-move-gen", bulkMoveGenHandler)
+	http.HandleFunc("/bulk-move-gen/stream", bulkMoveGenStreamHandler)
+	http.HandleFunc("/simulate", simulateHandler)
+	http.HandleFunc("/solve-endgame", solveEndgameHandler)
+	http.HandleFunc("/unseen-tiles", unseenTilesHandler)
+	http.HandleFunc("/analyze-game", analyzeGameHandler)
+	http.HandleFunc("/play-move", playMoveHandler)
+	http.HandleFunc("/pattern-search", patternSearchHandler)
+	http.HandleFunc("/hooks", hooksHandler)
+	http.HandleFunc("/jobs", createJobHandler)
+	http.HandleFunc("/jobs/{id}", jobHandler)
+	go jobs.evictLoop()
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -144,21 +190,21 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
 
+// serviceConfig is the macondo configuration set up by initService. The GCG
+// parser reads letter distributions and board layouts through it.
+var serviceConfig *config.Config
+
 func initService() error {
 	fmt.Println("=== Initializing Macondo Move Generation Service ===")
 	cfg := config.DefaultConfig()
 	cfg.Set("data-path", ".")
-	var err error
-	gd, err = kwg.GetKWG(cfg.WGLConfig(), "NWL23")
-	if err != nil {
-		return fmt.Errorf("failed to load lexicon: %v", err)
-	}
-	alph = gd.GetAlphabet()
-	ld, err = tilemapping.EnglishLetterDistribution(cfg.WGLConfig())
-	if err != nil {
-		return fmt.Errorf("failed to load letter distribution: %v", err)
+	serviceConfig = cfg
+	// macondo's game and GCG code log every turn at debug and trace level.
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	if err := loadLexica(cfg); err != nil {
+		return err
 	}
-	fmt.Println("✓ Loaded lexicon and letter distribution")
+	fmt.Printf("✓ Loaded lexica %s (default %s)\n", strings.Join(lexiconNames(), ", "), defaultLexicon.name)
 	return nil
 }
 
@@ -185,105 +231,205 @@ func generateMovesHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	pos, err := applyCGP(req.CGP, &req.Board, &req.Rack, &req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	if req.Rack == "" {
 		http.Error(w, "Rack is required", http.StatusBadRequest)
 		return
 	}
-	if len(req.Board) != 15 {
-		http.Error(w, "Board must have 15 rows", http.StatusBadRequest)
+	if err := checkBoardShape(req.Board); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.TopN <= 0 {
+		req.TopN = 10
+	}
+	if req.Sort != "" && req.Sort != "score" && req.Sort != "equity" {
+		http.Error(w, "Sort must be \"score\" or \"equity\"", http.StatusBadRequest)
+		return
+	}
+	if req.BagSize != nil && *req.BagSize < 0 {
+		http.Error(w, "BagSize cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if req.Offset < 0 || req.Limit < 0 {
+		http.Error(w, "Offset and Limit cannot be negative", http.StatusBadRequest)
 		return
 	}
-	for i := range req.Board {
-		if len(req.Board[i]) != 15 {
-			http.Error(w, "Each board row must have 15 columns", http.StatusBadRequest)
+	limit := req.TopN
+	if req.Limit > 0 {
+		limit = req.Limit
+	}
+	key := positionKey(req)
+	offset := req.Offset
+	if req.Cursor != "" {
+		if offset, err = decodeCursor(req.Cursor, key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
-	if req.TopN <= 0 {
-		req.TopN = 10
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	
-	// Create and initialize the board
-	bd := board.MakeBoard(board.CrosswordGameBoard)
-	
-	// Set letters on the board
-	tilesPlayed := 0
-	for row := 0; row < 15; row++ {
-		for col := 0; col < 15; col++ {
-			tile := req.Board[row][col]
-			if tile != "" {
-				if ml, err := alph.Val(tile); err == nil {
-					bd.SetLetter(row, col, ml)
-					tilesPlayed++
-				}
-			}
+	var filter *moveFilter
+	if req.Filter != nil {
+		if filter, err = req.Filter.compile(lex); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 	}
 	
-	// Manually set the tiles played count since SetLetter doesn't do this
-	bd.TestSetTilesPlayed(tilesPlayed)
+	bd, err := loadBoard(req.Board, lex)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	
-	// Generate cross-sets and update anchors
-	cross_set.GenAllCrossSets(bd, gd, ld)
-	bd.UpdateAllAnchors()
+	rack := tilemapping.RackFromString(req.Rack, lex.alph)
+	bagSize := defaultBagSize(lex.ld, bd.GetTilesPlayed(), int(rack.NumTiles()))
+	if req.BagSize != nil {
+		bagSize = *req.BagSize
+	}
 	
-	rack := tilemapping.RackFromString(req.Rack, alph)
-	generator := movegen.NewGordonGenerator(gd, bd, ld)
-	moves := generator.GenAll(rack, false)
+	// Exchanging needs a full rack's worth of tiles in the bag. GenAll
+	// already returns a pass when there is no play.
+	generator := movegen.NewGordonGenerator(lex.gd, bd, lex.ld)
+	moves := generator.GenAll(rack, req.IncludeExchanges && bagSize >= rackSize)
+	if req.IncludeExchanges && !hasPass(moves) {
+		moves = append(moves, move.NewPassMove(rack.TilesOn(), lex.alph))
+	}
 	
 	fmt.Printf("Generated %d moves for rack '%s'\n", len(moves), req.Rack)
 	
-	responseMoves := make([]Move, 0, req.TopN)
-	for i, m := range moves {
-		if i >= req.TopN {
-			break
-		}
-		
-		// Extract word from move string
-		moveStr := m.String()
-		word := ""
-		
-		// Parse move string to extract word
-		// Format: "<action: play word: POSITION WORD score: SCORE tp: TILES_PLAYED leave: LEAVE>"
-		if strings.Contains(moveStr, "play word:") {
-			parts := strings.Split(moveStr, "play word:")
-			if len(parts) > 1 {
-				wordPart := strings.TrimSpace(parts[1])
-				// Split by spaces and find the word (skip position)
-				wordFields := strings.Fields(wordPart)
-				for _, field := range wordFields {
-					// Skip position-like strings (like "8D") and score info
-					if len(field) >= 2 && !strings.ContainsAny(field, "0123456789") && 
-					   !strings.HasPrefix(field, "score:") && 
-					   !strings.HasPrefix(field, "tp:") && 
-					   !strings.HasPrefix(field, "leave:") {
-						// Found the word, but check if it's not just dots
-						if !strings.HasPrefix(field, ".....") {
-							word = field
-							break
-						}
-						break
-					}
-				}
+	if filter != nil {
+		kept := moves[:0]
+		for _, m := range moves {
+			if filter.match(m, bd, lex) {
+				kept = append(kept, m)
 			}
 		}
-		
-		responseMoves = append(responseMoves, Move{
-			Position: m.BoardCoords(),
-			Word:     word,
-			Score:    m.Score(),
-			Leave:    m.Leave().UserVisible(alph),
-		})
+		moves = kept
+	}
+	
+	setEquities(moves, bd, lex, bagWithTiles(lex, bagSize))
+	
+	sortMoves(moves, bd, lex, req.Sort == "equity")
+	end := offset + limit
+	if end > len(moves) {
+		end = len(moves)
+	}
+	responseMoves := []Move{}
+	for i := offset; i < end; i++ {
+		responseMoves = append(responseMoves, moveToResponse(moves[i], bd, lex))
 	}
 	resp := GenerateMovesResponse{
-		Moves: responseMoves,
-		Total: len(moves),
+		Moves:   responseMoves,
+		Total:   len(moves),
+		Offset:  offset,
+		Lexicon: lex.name,
+		CGP:     toCGP(bd, lex, [2]string{req.Rack, pos.Racks[1]}, pos.Scores, pos.ScorelessTurns),
+	}
+	if end < len(moves) {
+		resp.NextCursor = encodeCursor(key, end)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// moveToResponse converts a generated move into its JSON form. The word is
+// rebuilt from the move's machine letters, filling played-through squares
+// (which macondo encodes as 0) from the board. The move's equity must
+// already have been set.
+func moveToResponse(m *move.Move, bd *board.GameBoard, lex *lexicon) Move {
+	if m.Action() != move.MoveTypePlay {
+		resp := Move{
+			Action:     "pass",
+			FromRack:   []bool{},
+			Blanks:     []int{},
+			Leave:      m.Leave().UserVisible(lex.alph),
+			LeaveValue: lex.calc.LeaveValue(m.Leave()),
+			Equity:     m.Equity(),
+		}
+		if m.Action() == move.MoveTypeExchange {
+			resp.Action = "exchange"
+			resp.Exchanged = m.Tiles().UserVisible(lex.alph)
+		}
+		return resp
+	}
+
+	row, col, vertical := m.CoordsAndVertical()
+	direction := "across"
+	if vertical {
+		direction = "down"
+	}
+
+	tiles := m.Tiles()
+	fromRack := make([]bool, len(tiles))
+	for i, ml := range tiles {
+		fromRack[i] = ml != 0
+	}
+	word, blanks := playWord(m, bd, lex)
+
+	words, bonus := scoreBreakdown(m, bd, lex)
+	return Move{
+		Action:      "play",
+		Position:    m.BoardCoords(),
+		Row:         row,
+		Col:         col,
+		Direction:   direction,
+		Word:        word,
+		FromRack:    fromRack,
+		Blanks:      blanks,
+		TilesPlayed: m.TilesPlayed(),
+		Score:       m.Score(),
+		Leave:       m.Leave().UserVisible(lex.alph),
+		LeaveValue:  lex.calc.LeaveValue(m.Leave()),
+		Equity:      m.Equity(),
+		Words:       words,
+		BingoBonus:  bonus,
+	}
+}
+
+// hasPass reports whether moves includes a pass.
+func hasPass(moves []*move.Move) bool {
+	for _, m := range moves {
+		if m.Action() == move.MoveTypePass {
+			return true
+		}
+	}
+	return false
+}
+
+// playWord returns the main word of play m on bd, including the tiles it
+// plays through (which macondo encodes as 0), with blanks shown as the
+// letters they stand for, and the indexes in the word of its blanks.
+func playWord(m *move.Move, bd *board.GameBoard, lex *lexicon) (string, []int) {
+	row, col, vertical := m.CoordsAndVertical()
+	var word strings.Builder
+	blanks := []int{}
+	for i, ml := range m.Tiles() {
+		if ml == 0 {
+			if vertical {
+				ml = bd.GetLetter(row+i, col)
+			} else {
+				ml = bd.GetLetter(row, col+i)
+			}
+		}
+		if ml.IsBlanked() {
+			blanks = append(blanks, i)
+		}
+		word.WriteString(lex.alph.Letter(ml.Unblank()))
+	}
+	return word.String(), blanks
+}
+
 func validateWordHandler(w http.ResponseWriter, r *http.Request) {
 	setCORSHeaders(w, r)
 	if r.Method == http.MethodOptions {
@@ -306,69 +452,19 @@ func validateWordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Convert word to uppercase for consistency with lexicon
 	word := strings.ToUpper(strings.TrimSpace(req.Word))
 	
-	// Check if word exists in the loaded lexicon using existing logic
-	// We'll use the move generator to validate - if we can generate moves for this word, it's valid
-	var isValid bool
-	
-	// Create an empty board
-	bd := board.MakeBoard(board.CrosswordGameBoard)
-	
-	// Try to create a rack with the letters from the word
-	// This will fail if the word contains invalid characters
-	rack := tilemapping.RackFromString(word, alph)
-	if rack == nil {
-		isValid = false
-	} else {
-		// Generate cross-sets for the empty board
-		cross_set.GenAllCrossSets(bd, gd, ld)
-		bd.UpdateAllAnchors()
-		
-		// Try to generate moves - if any moves are generated, the word is valid
-		generator := movegen.NewGordonGenerator(gd, bd, ld)
-		moves := generator.GenAll(rack, false)
-		
-		// Check if any of the generated moves contain our word as a playable word
-		for _, m := range moves {
-			moveStr := m.String()
-			// Only consider moves that are actual word plays, not passes
-			if strings.Contains(moveStr, "play word:") {
-				// Extract the word from the move string using the same logic as other handlers
-				if strings.Contains(moveStr, "play word:") {
-					parts := strings.Split(moveStr, "play word:")
-					if len(parts) > 1 {
-						wordPart := strings.TrimSpace(parts[1])
-						wordFields := strings.Fields(wordPart)
-						for _, field := range wordFields {
-							if len(field) >= 2 && !strings.ContainsAny(field, "0123456789") && 
-							   !strings.HasPrefix(field, "score:") && 
-							   !strings.HasPrefix(field, "tp:") && 
-							   !strings.HasPrefix(field, "leave:") {
-								if !strings.HasPrefix(field, ".....") {
-									if field == word {
-										isValid = true
-										break
-									}
-									break
-								}
-								break
-							}
-						}
-					}
-				}
-				if isValid {
-					break
-				}
-			}
-		}
-	}
-	
 	response := ValidateWordResponse{
 		Word:      word,
-		IsValid:   isValid,
-		Lexicon:   "NWL23", // Using the same lexicon that's already loaded
+		IsValid:   isValidWord(lex.gd, word),
+		Lexicon:   lex.name,
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -396,8 +492,13 @@ func validateWordsHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Words array is required", http.StatusBadRequest)
 		return
 	}
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	
-	// Validate each word using the same logic as validateWordHandler
 	validations := make([]WordValidation, 0, len(req.Words))
 	validCount := 0
 	invalidCount := 0
@@ -405,63 +506,17 @@ func validateWordsHandler(w http.ResponseWriter, r *http.Request) {
 	for _, word := range req.Words {
 		// Convert word to uppercase for consistency with lexicon
 		word = strings.ToUpper(strings.TrimSpace(word))
+		isValid := isValidWord(lex.gd, word)
 		
-		// Check if word exists in the loaded lexicon using the same logic as validateWordHandler
-		var isValid bool
-		
-		// Create an empty board
-		bd := board.MakeBoard(board.CrosswordGameBoard)
-		
-		// Try to create a rack with the letters from the word
-		rack := tilemapping.RackFromString(word, alph)
-		if rack != nil {
-			// Generate cross-sets for the empty board
-			cross_set.GenAllCrossSets(bd, gd, ld)
-			bd.UpdateAllAnchors()
-			
-			// Try to generate moves - if any moves are generated, the word is valid
-			generator := movegen.NewGordonGenerator(gd, bd, ld)
-			moves := generator.GenAll(rack, false)
-			
-			// Check if any of the generated moves contain our word as a playable word
-			for _, m := range moves {
-				moveStr := m.String()
-				// Only consider moves that are actual word plays, not passes
-				if strings.Contains(moveStr, "play word:") {
-					// Extract the word from the move string using the same logic as other handlers
-					if strings.Contains(moveStr, "play word:") {
-						parts := strings.Split(moveStr, "play word:")
-						if len(parts) > 1 {
-							wordPart := strings.TrimSpace(parts[1])
-							wordFields := strings.Fields(wordPart)
-							for _, field := range wordFields {
-								if len(field) >= 2 && !strings.ContainsAny(field, "0123456789") && 
-								   !strings.HasPrefix(field, "score:") && 
-								   !strings.HasPrefix(field, "tp:") && 
-								   !strings.HasPrefix(field, "leave:") {
-									if !strings.HasPrefix(field, ".....") {
-										if field == word {
-											isValid = true
-											break
-										}
-										break
-									}
-									break
-								}
-							}
-						}
-					}
-					if isValid {
-						break
-					}
-				}
-			}
-		}
-		
-		validations = append(validations, WordValidation{
+		validation := WordValidation{
 			Word:    word,
 			IsValid: isValid,
-		})
+		}
+		if req.IncludeHooks {
+			hooks := findHooks(lex, word, req.IncludeInnerHooks)
+			validation.Hooks = &hooks
+		}
+		validations = append(validations, validation)
 		
 		if isValid {
 			validCount++
@@ -475,185 +530,7 @@ func validateWordsHandler(w http.ResponseWriter, r *http.Request) {
 		Count:   len(req.Words),
 		Valid:   validCount,
 		Invalid: invalidCount,
-		Lexicon: "NWL23",
-	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func findSubanagramsHandler(w http.ResponseWriter, r *http.Request) {
-	setCORSHeaders(w, r)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-	
-	var req SubanagramSearchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
-	}
-
-	if req.Letters == "" {
-		http.Error(w, "Letters are required", http.StatusBadRequest)
-		return
-	}
-
-	// Convert letters to uppercase and remove spaces
-	letters := strings.ToUpper(strings.ReplaceAll(req.Letters, " ", ""))
-	
-	// Create an empty board
-	bd := board.MakeBoard(board.CrosswordGameBoard)
-	
-	// Try to create a rack with the letters
-	rack := tilemapping.RackFromString(letters, alph)
-	if rack == nil {
-		http.Error(w, "Invalid letters provided", http.StatusBadRequest)
-		return
-	}
-	
-	// Generate cross-sets for the empty board
-	cross_set.GenAllCrossSets(bd, gd, ld)
-	bd.UpdateAllAnchors()
-	
-	// Generate all possible moves
-	generator := movegen.NewGordonGenerator(gd, bd, ld)
-	moves := generator.GenAll(rack, false)
-	
-	// Extract unique words from the moves
-	subanagrams := make(map[string]bool)
-	for _, m := range moves {
-		moveStr := m.String()
-		// Parse move string to extract word (same logic as generateMovesHandler)
-		if strings.Contains(moveStr, "play word:") {
-			parts := strings.Split(moveStr, "play word:")
-			if len(parts) > 1 {
-				wordPart := strings.TrimSpace(parts[1])
-				wordFields := strings.Fields(wordPart)
-				for _, field := range wordFields {
-					if len(field) >= 2 && !strings.ContainsAny(field, "0123456789") && 
-					   !strings.HasPrefix(field, "score:") && 
-					   !strings.HasPrefix(field, "tp:") && 
-					   !strings.HasPrefix(field, "leave:") {
-						if !strings.HasPrefix(field, ".....") {
-							subanagrams[field] = true
-							break
-						}
-						break
-					}
-				}
-			}
-		}
-	}
-	
-	// Convert map keys to slice and sort
-	subanagramList := make([]string, 0, len(subanagrams))
-	for word := range subanagrams {
-		subanagramList = append(subanagramList, word)
-	}
-	sort.Strings(subanagramList)
-	
-	response := SubanagramSearchResponse{
-		Letters:      letters,
-		Subanagrams:  subanagramList,
-		Count:        len(subanagramList),
-		Lexicon:      "NWL23",
-	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func findAnagramsHandler(w http.ResponseWriter, r *http.Request) {
-	setCORSHeaders(w, r)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-	
-	var req AnagramSearchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
-	}
-
-	if req.Letters == "" {
-		http.Error(w, "Letters are required", http.StatusBadRequest)
-		return
-	}
-
-	// Convert letters to uppercase and remove spaces
-	letters := strings.ToUpper(strings.ReplaceAll(req.Letters, " ", ""))
-	inputLength := len(letters)
-	
-	// Create an empty board
-	bd := board.MakeBoard(board.CrosswordGameBoard)
-	
-	// Try to create a rack with the letters
-	rack := tilemapping.RackFromString(letters, alph)
-	if rack == nil {
-		http.Error(w, "Invalid letters provided", http.StatusBadRequest)
-		return
-	}
-	
-	// Generate cross-sets for the empty board
-	cross_set.GenAllCrossSets(bd, gd, ld)
-	bd.UpdateAllAnchors()
-	
-	// Generate all possible moves
-	generator := movegen.NewGordonGenerator(gd, bd, ld)
-	moves := generator.GenAll(rack, false)
-	
-	// Extract unique words from the moves that are the exact same length
-	anagrams := make(map[string]bool)
-	for _, m := range moves {
-		moveStr := m.String()
-		// Parse move string to extract word (same logic as generateMovesHandler)
-		if strings.Contains(moveStr, "play word:") {
-			parts := strings.Split(moveStr, "play word:")
-			if len(parts) > 1 {
-				wordPart := strings.TrimSpace(parts[1])
-				wordFields := strings.Fields(wordPart)
-				for _, field := range wordFields {
-					if len(field) >= 2 && !strings.ContainsAny(field, "0123456789") && 
-					   !strings.HasPrefix(field, "score:") && 
-					   !strings.HasPrefix(field, "tp:") && 
-					   !strings.HasPrefix(field, "leave:") {
-						if !strings.HasPrefix(field, ".....") {
-							// Only include words of the exact same length
-							if len(field) == inputLength {
-								anagrams[field] = true
-							}
-							break
-						}
-						break
-					}
-				}
-			}
-		}
-	}
-	
-	// Convert map keys to slice and sort
-	anagramList := make([]string, 0, len(anagrams))
-	for word := range anagrams {
-		anagramList = append(anagramList, word)
-	}
-	sort.Strings(anagramList)
-	
-	response := AnagramSearchResponse{
-		Letters:  letters,
-		Anagrams: anagramList,
-		Count:    len(anagramList),
-		Lexicon:  "NWL23",
+		Lexicon: lex.name,
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -676,120 +553,32 @@ func bulkMoveGenHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
-
-	if req.TilePool == "" {
-		http.Error(w, "TilePool is required", http.StatusBadRequest)
+	if _, err := applyCGP(req.CGP, &req.Board, &req.Rack, &req.Lexicon); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	
-	if len(req.Board) != 15 {
-		http.Error(w, "Board must have 15 rows", http.StatusBadRequest)
+
+	run, err := newBulkRun(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	for i := range req.Board {
-		if len(req.Board[i]) != 15 {
-			http.Error(w, "Each board row must have 15 columns", http.StatusBadRequest)
-			return
-		}
-	}
-	
-	if req.Iterations <= 0 {
-		req.Iterations = 1000
-	}
-	
-	// Convert tile pool to uppercase and remove spaces
-	tilePool := strings.ToUpper(strings.ReplaceAll(req.TilePool, " ", ""))
-	
-	// Create and initialize the board
-	bd := board.MakeBoard(board.CrosswordGameBoard)
-	
-	// Set letters on the board
-	tilesPlayed := 0
-	for row := 0; row < 15; row++ {
-		for col := 0; col < 15; col++ {
-			tile := req.Board[row][col]
-			if tile != "" {
-				if ml, err := alph.Val(tile); err == nil {
-					bd.SetLetter(row, col, ml)
-					tilesPlayed++
-				}
-			}
-		}
-	}
-	
-	// Manually set the tiles played count since SetLetter doesn't do this
-	bd.TestSetTilesPlayed(tilesPlayed)
-	
-	// Generate cross-sets for the empty board
-	cross_set.GenAllCrossSets(bd, gd, ld)
-	bd.UpdateAllAnchors()
-	
-	// Initialize random seed
-	rand.Seed(time.Now().UnixNano())
-	
-	// Statistics tracking
-	totalScore := 0
-	totalBingos := 0
 	
-	fmt.Printf("Starting bulk move generation with %d iterations...\n", req.Iterations)
-	
-	// Run iterations
-	for i := 0; i < req.Iterations; i++ {
-		// Generate random 7-tile rack from pool
-		rack := generateRandomRack(tilePool, 7, alph)
-		if rack == nil {
-			continue // Skip if we can't generate a valid rack
-		}
-		
-		// Generate moves for this rack
-		generator := movegen.NewGordonGenerator(gd, bd, ld)
-		moves := generator.GenAll(rack, false)
-		
-		if len(moves) > 0 {
-			// Get the top move (first move)
-			topMove := moves[0]
-			score := topMove.Score()
-			totalScore += score
-			
-			// Check if it's a bingo (7 tiles played)
-			leave := topMove.Leave()
-			if leave != nil {
-				leaveStr := leave.UserVisible(alph)
-				// If leave is empty or very short, it's likely a bingo
-				if len(strings.TrimSpace(leaveStr)) <= 1 {
-					totalBingos++
-				}
-			}
-		}
-		
-		// Progress update every 100 iterations
-		if (i+1)%100 == 0 {
-			fmt.Printf("Completed %d/%d iterations...\n", i+1, req.Iterations)
-		}
-	}
-	
-	// Calculate statistics
-	averageScore := float64(totalScore) / float64(req.Iterations)
-	bingoPercent := float64(totalBingos) / float64(req.Iterations) * 100.0
-	
-	response := BulkMoveGenResponse{
-		Iterations:   req.Iterations,
-		AverageScore: averageScore,
-		BingoPercent: bingoPercent,
-		TotalBingos:  totalBingos,
-		TotalScore:   totalScore,
-		Lexicon:      "NWL23",
+	fmt.Printf("Starting bulk move generation with %d iterations on %d workers...\n", run.iterations, run.workers)
+	response, err := run.run(r.Context(), nil)
+	if err != nil {
+		return // The client went away.
 	}
 	
 	fmt.Printf("Bulk move generation complete. Average score: %.2f, Bingo rate: %.2f%%\n", 
-		averageScore, bingoPercent)
+		response.AverageScore, response.BingoPercent)
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
 // generateRandomRack creates a random rack of specified size from the given tile pool
-func generateRandomRack(tilePool string, size int, alph *tilemapping.TileMapping) *tilemapping.Rack {
+func generateRandomRack(rng *rand.Rand, tilePool string, size int, alph *tilemapping.TileMapping) *tilemapping.Rack {
 	// Convert tile pool to a slice of individual tiles
 	var tiles []string
 	for _, char := range tilePool {
@@ -803,7 +592,7 @@ func generateRandomRack(tilePool string, size int, alph *tilemapping.TileMapping
 	// Shuffle the tiles and take the first 'size' tiles
 	shuffled := make([]string, len(tiles))
 	copy(shuffled, tiles)
-	rand.Shuffle(len(shuffled), func(i, j int) {
+	rng.Shuffle(len(shuffled), func(i, j int) {
 		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
 	})
 	
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/move"
+)
+
+// sortMoves puts moves, on bd, in the order /generate-moves pages through
+// them: highest equity (or score) first, then most tiles played, then by
+// coordinates (row, column, across before down), then alphabetically by
+// word. Exchanges and passes come after plays that tie with them, the pass
+// last, and exchanges in alphabetical order of the tiles thrown back. No two
+// distinct moves compare equal, so the order is the same on every call.
+// The keys are read straight off the moves, so sorting doesn't need the
+// full JSON form of each one.
+func sortMoves(moves []*move.Move, bd *board.GameBoard, lex *lexicon, byEquity bool) {
+	type keyedMove struct {
+		m         *move.Move
+		tiles     int // tiles played; macondo counts exchanged tiles too
+		rank      int // plays, then exchanges, then the pass
+		row, col  int
+		down      bool
+		word      string
+		exchanged string
+		blanks    string
+	}
+	keyed := make([]keyedMove, len(moves))
+	for i, m := range moves {
+		k := keyedMove{m: m}
+		switch m.Action() {
+		case move.MoveTypePlay:
+			var blanks []int
+			k.tiles = m.TilesPlayed()
+			k.row, k.col, k.down = m.CoordsAndVertical()
+			k.word, blanks = playWord(m, bd, lex)
+			k.blanks = fmt.Sprint(blanks)
+		case move.MoveTypeExchange:
+			k.rank = 1
+			k.exchanged = m.Tiles().UserVisible(lex.alph)
+		default:
+			k.rank = 2
+		}
+		keyed[i] = k
+	}
+
+	sort.SliceStable(keyed, func(i, j int) bool {
+		a, b := &keyed[i], &keyed[j]
+		if byEquity && a.m.Equity() != b.m.Equity() {
+			return a.m.Equity() > b.m.Equity()
+		}
+		if a.m.Score() != b.m.Score() {
+			return a.m.Score() > b.m.Score()
+		}
+		if a.tiles != b.tiles {
+			return a.tiles > b.tiles
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.row != b.row {
+			return a.row < b.row
+		}
+		if a.col != b.col {
+			return a.col < b.col
+		}
+		if a.down != b.down {
+			return !a.down
+		}
+		if a.word != b.word {
+			return a.word < b.word
+		}
+		if a.exchanged != b.exchanged {
+			return a.exchanged < b.exchanged
+		}
+		// Only a blank's placement is left to tell the plays apart.
+		return a.blanks < b.blanks
+	})
+	for i, k := range keyed {
+		moves[i] = k.m
+	}
+}
+
+// positionKey fingerprints everything in req that decides the sorted move
+// list, so a cursor can be checked against the request it is used with.
+func positionKey(req GenerateMovesRequest) string {
+	req.TopN, req.Offset, req.Limit, req.Cursor = 0, 0, 0, ""
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// encodeCursor returns an opaque cursor for the move list of the position
+// with the given key, starting at offset.
+func encodeCursor(key string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key + ":" + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the offset a cursor points at, or an error if it is
+// malformed or was issued for a different position than key.
+func decodeCursor(cursor, key string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	cursorKey, offset, ok := strings.Cut(string(data), ":")
+	n, err := strconv.Atoi(offset)
+	if !ok || err != nil || n < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	if cursorKey != key {
+		return 0, errors.New("cursor was issued for a different position")
+	}
+	return n, nil
+}
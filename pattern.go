@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/domino14/word-golib/kwg"
+	"github.com/domino14/word-golib/tilemapping"
+)
+
+// defaultPatternLimit caps /pattern-search results when the request doesn't.
+const defaultPatternLimit = 1000
+
+// PatternSearchRequest is the body of /pattern-search. In Pattern, "?"
+// stands for any one letter, "*" for any run of letters (none included),
+// and a bracket for one of the letters in it, e.g. "[AEIOU]", or with "^"
+// first, one not in it. An empty pattern matches every word. Required
+// letters must all appear in the word, repeated letters as often as given.
+// Forbidden letters may not fill a "?" or "*", so "QU?????" with U
+// forbidden finds 7-letter words with no U after the QU. Sort is
+// "alphabetical" (the default) or "probability", which orders words by
+// length and then most likely draw first.
+type PatternSearchRequest struct {
+	Pattern   string `json:"pattern"`
+	Required  string `json:"required,omitempty"`
+	Forbidden string `json:"forbidden,omitempty"`
+	MinLength int    `json:"minLength,omitempty"`
+	MaxLength int    `json:"maxLength,omitempty"`
+	Sort      string `json:"sort,omitempty"`
+	Limit     int    `json:"limit,omitempty"` // default 1000
+	Lexicon   string `json:"lexicon,omitempty"`
+	// IncludeStudyInfo adds each word's alphagram and probability.
+	IncludeStudyInfo bool `json:"includeStudyInfo,omitempty"`
+}
+
+// PatternMatch is a word that matches the pattern, with its StudyInfo if
+// it was asked for.
+type PatternMatch struct {
+	Word string `json:"word"`
+	*StudyInfo
+}
+
+// PatternSearchResponse lists up to Limit matches in the requested order;
+// Total counts them all.
+type PatternSearchResponse struct {
+	Pattern string         `json:"pattern"`
+	Words   []PatternMatch `json:"words"`
+	Count   int            `json:"count"`
+	Total   int            `json:"total"`
+	Lexicon string         `json:"lexicon"`
+}
+
+func patternSearchHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PatternSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Sort != "" && req.Sort != "alphabetical" && req.Sort != "probability" {
+		http.Error(w, "sort must be \"alphabetical\" or \"probability\"", http.StatusBadRequest)
+		return
+	}
+	if req.Limit < 0 {
+		http.Error(w, "limit cannot be negative", http.StatusBadRequest)
+		return
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPatternLimit
+	}
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := compilePattern(&req, lex)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var words []tilemapping.MachineWord
+	p.walk(p.gd.ArcIndex(0), p.closure(1), nil, func(word tilemapping.MachineWord) {
+		words = append(words, append(tilemapping.MachineWord(nil), word...))
+	})
+	if req.Sort == "probability" {
+		less := probabilityLess(lex.ld)
+		sort.SliceStable(words, func(i, j int) bool { return less(words[i], words[j]) })
+	}
+
+	response := PatternSearchResponse{
+		Pattern: strings.ToUpper(req.Pattern),
+		Words:   []PatternMatch{},
+		Total:   len(words),
+		Lexicon: lex.name,
+	}
+	for _, word := range words[:min(limit, len(words))] {
+		match := PatternMatch{Word: word.UserVisible(lex.alph)}
+		if req.IncludeStudyInfo {
+			match.StudyInfo = studyInfo(lex, word)
+		}
+		response.Words = append(response.Words, match)
+	}
+	response.Count = len(response.Words)
+	fmt.Printf("Pattern '%s' matched %d words\n", req.Pattern, response.Total)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// patternToken is one position of a compiled pattern: the letters that may
+// fill it, as a bit set indexed by machine letter, and whether it repeats.
+type patternToken struct {
+	letters uint64
+	star    bool
+}
+
+// pattern is a PatternSearchRequest compiled for walking a KWG. The walk
+// tracks the pattern positions a prefix could have reached as a bit set of
+// token indexes; reaching len(tokens) means the whole pattern matched.
+type pattern struct {
+	gd        *kwg.KWG
+	tokens    []patternToken
+	required  []int
+	minLength int
+	maxLength int
+}
+
+// compilePattern checks req and compiles it for lex.
+func compilePattern(req *PatternSearchRequest, lex *lexicon) (*pattern, error) {
+	p := &pattern{gd: lex.gd, minLength: req.MinLength, maxLength: req.MaxLength}
+	if p.minLength < 0 || p.maxLength < 0 {
+		return nil, errors.New("minLength and maxLength cannot be negative")
+	}
+	if p.maxLength == 0 || p.maxLength > maxWordLength {
+		p.maxLength = maxWordLength
+	}
+	if p.minLength > p.maxLength {
+		return nil, errors.New("minLength cannot be more than maxLength")
+	}
+
+	numLetters := int(lex.alph.NumLetters())
+	var all uint64
+	for ml := 1; ml < numLetters; ml++ {
+		all |= 1 << ml
+	}
+	letterOf := func(r rune) (tilemapping.MachineLetter, error) {
+		ml, err := lex.alph.Val(string(r))
+		if err != nil || ml == 0 {
+			return 0, fmt.Errorf("invalid letter %q", r)
+		}
+		return ml, nil
+	}
+
+	forbidden, err := tilemapping.ToMachineLetters(strings.ToUpper(strings.ReplaceAll(req.Forbidden, " ", "")), lex.alph)
+	if err != nil {
+		return nil, fmt.Errorf("invalid forbidden letters %q", req.Forbidden)
+	}
+	wild := all
+	for _, ml := range forbidden {
+		wild &^= 1 << ml
+	}
+	required, err := tilemapping.ToMachineLetters(strings.ToUpper(strings.ReplaceAll(req.Required, " ", "")), lex.alph)
+	if err != nil {
+		return nil, fmt.Errorf("invalid required letters %q", req.Required)
+	}
+	p.required = make([]int, numLetters)
+	for _, ml := range required {
+		if ml == 0 {
+			return nil, fmt.Errorf("invalid required letters %q", req.Required)
+		}
+		p.required[ml]++
+	}
+
+	text := []rune(strings.ToUpper(strings.ReplaceAll(req.Pattern, " ", "")))
+	if len(text) == 0 {
+		text = []rune{'*'}
+	}
+	fixed := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '*':
+			if n := len(p.tokens); n == 0 || !p.tokens[n-1].star {
+				p.tokens = append(p.tokens, patternToken{letters: wild, star: true})
+			}
+			continue
+		case '?':
+			p.tokens = append(p.tokens, patternToken{letters: wild})
+		case '[':
+			end := i + 1
+			for end < len(text) && text[end] != ']' {
+				end++
+			}
+			if end == len(text) {
+				return nil, errors.New("unclosed bracket in pattern")
+			}
+			class := text[i+1 : end]
+			negate := len(class) > 0 && class[0] == '^'
+			if negate {
+				class = class[1:]
+			}
+			if len(class) == 0 {
+				return nil, errors.New("empty bracket in pattern")
+			}
+			var letters uint64
+			for _, r := range class {
+				ml, err := letterOf(r)
+				if err != nil {
+					return nil, fmt.Errorf("pattern: %v", err)
+				}
+				letters |= 1 << ml
+			}
+			if negate {
+				letters = all &^ letters
+			}
+			p.tokens = append(p.tokens, patternToken{letters: letters})
+			i = end
+		default:
+			ml, err := letterOf(text[i])
+			if err != nil {
+				return nil, fmt.Errorf("pattern: %v", err)
+			}
+			p.tokens = append(p.tokens, patternToken{letters: 1 << ml})
+		}
+		fixed++
+	}
+	if fixed > maxWordLength {
+		return nil, fmt.Errorf("pattern is longer than %d letters", maxWordLength)
+	}
+	return p, nil
+}
+
+// closure adds to states the positions reachable by letting stars match
+// nothing.
+func (p *pattern) closure(states uint64) uint64 {
+	for i, t := range p.tokens {
+		if t.star && states&(1<<i) != 0 {
+			states |= 1 << (i + 1)
+		}
+	}
+	return states
+}
+
+// step returns the positions reached from states by matching ml.
+func (p *pattern) step(states uint64, ml tilemapping.MachineLetter) uint64 {
+	var next uint64
+	for i, t := range p.tokens {
+		if states&(1<<i) == 0 || t.letters&(1<<ml) == 0 {
+			continue
+		}
+		if t.star {
+			next |= 1 << i
+		} else {
+			next |= 1 << (i + 1)
+		}
+	}
+	return p.closure(next)
+}
+
+// walk calls found for every word below node, in the DAWG half of a KWG,
+// that extends word and matches the pattern from states.
+func (p *pattern) walk(node uint32, states uint64, word tilemapping.MachineWord, found func(tilemapping.MachineWord)) {
+	accept := uint64(1) << len(p.tokens)
+	for ; ; node++ {
+		ml := tilemapping.MachineLetter(p.gd.Tile(node))
+		if next := p.step(states, ml); next != 0 {
+			word = append(word, ml)
+			if p.gd.Accepts(node) && next&accept != 0 && len(word) >= p.minLength && p.hasRequired(word) {
+				found(word)
+			}
+			if arc := p.gd.ArcIndex(node); arc != 0 && len(word) < p.maxLength {
+				p.walk(arc, next, word, found)
+			}
+			word = word[:len(word)-1]
+		}
+		if p.gd.IsEnd(node) {
+			return
+		}
+	}
+}
+
+// hasRequired reports whether word holds every required letter.
+func (p *pattern) hasRequired(word tilemapping.MachineWord) bool {
+	counts := make([]int, len(p.required))
+	for _, ml := range word {
+		counts[ml]++
+	}
+	for ml, n := range p.required {
+		if counts[ml] < n {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/domino14/word-golib/tilemapping"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/move"
+)
+
+type PlayMoveRequest struct {
+	Board   [][]BoardCell `json:"board"` // 15x15 board; lowercase letters are blanks
+	Rack    string        `json:"rack"`
+	Move    string        `json:"move"` // e.g. "8D HELLO", "H8 QUA(I)NT"; lowercase letters are blanks
+	Lexicon string        `json:"lexicon,omitempty"`
+	CGP     string        `json:"cgp,omitempty"` // Position in CGP notation; replaces board
+}
+
+// IllegalReason is one reason a play is not allowed. Code is one of
+// "off_board", "occupied", "play_through_mismatch", "word_continues",
+// "no_tiles_placed", "too_short", "not_on_center", "not_connected",
+// "not_in_rack", or "phony".
+type IllegalReason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PlayMoveResponse gives the result of a play. When the tiles are placed
+// legally Move is set, with the score and formed words, even if some word
+// is phony; Board, Rack, and CGP describe the position after the play and
+// are only set when it is legal.
+type PlayMoveResponse struct {
+	Legal   bool            `json:"legal"`
+	Reasons []IllegalReason `json:"reasons"`
+	Move    *Move           `json:"move,omitempty"`
+	Score   int             `json:"score"`
+	Board   [][]string      `json:"board,omitempty"` // lowercase letters are blanks
+	Rack    string          `json:"rack"`            // tiles left on the rack
+	CGP     string          `json:"cgp,omitempty"`
+	Lexicon string          `json:"lexicon"`
+}
+
+func playMoveHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PlayMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	pos, err := applyCGP(req.CGP, &req.Board, &req.Rack, &req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Rack == "" || req.Move == "" {
+		http.Error(w, "Rack and Move are required", http.StatusBadRequest)
+		return
+	}
+	if err := checkBoardShape(req.Board); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bd, err := loadBoard(req.Board, lex)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	play, err := parsePlay(req.Move, lex)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rack, err := tilemapping.ToMachineWord(strings.ToUpper(strings.ReplaceAll(req.Rack, " ", "")), lex.alph)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid rack %q", req.Rack), http.StatusBadRequest)
+		return
+	}
+
+	response := PlayMoveResponse{Reasons: []IllegalReason{}, Rack: req.Rack, Lexicon: lex.name}
+	m, reasons := placePlay(bd, lex, play, rack)
+	response.Reasons = append(response.Reasons, reasons...)
+	if m != nil {
+		resp := moveToResponse(m, bd, lex)
+		response.Move = &resp
+		response.Score = m.Score()
+		for _, word := range resp.Words {
+			if !isValidWord(lex.gd, word.Word) {
+				response.Reasons = append(response.Reasons, IllegalReason{"phony", fmt.Sprintf("%s is not in %s", word.Word, lex.name)})
+			}
+		}
+	}
+
+	if len(response.Reasons) == 0 {
+		response.Legal = true
+		bd.PlayMove(m)
+		response.Board = boardStrings(bd, lex)
+		response.Rack = m.Leave().UserVisible(lex.alph)
+		response.CGP = toCGP(bd, lex, [2]string{response.Rack, pos.Racks[1]}, pos.Scores, pos.ScorelessTurns)
+	}
+	fmt.Printf("Played '%s' with rack '%s': legal=%v\n", req.Move, req.Rack, response.Legal)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parsedPlay is a play in standard notation. A letter marked through must
+// already be on the board; 0 stands for a played-through tile whose letter
+// wasn't given (".").
+type parsedPlay struct {
+	row, col int
+	vertical bool
+	letters  []tilemapping.MachineLetter
+	through  []bool
+}
+
+// parsePlay reads a play such as "8D HELLO" or "H8 QUA(I)NT". Letters in
+// parentheses, or written as ".", are already on the board.
+func parsePlay(notation string, lex *lexicon) (*parsedPlay, error) {
+	fields := strings.Fields(notation)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("move %q must be coordinates and a word, e.g. \"8D HELLO\"", notation)
+	}
+	row, col, vertical, err := parseCoords(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parsedPlay{row: row, col: col, vertical: vertical}
+	inParens := false
+	for _, r := range fields[1] {
+		switch {
+		case r == '(' && !inParens:
+			inParens = true
+		case r == ')' && inParens:
+			inParens = false
+		case r == '.':
+			p.letters = append(p.letters, 0)
+			p.through = append(p.through, true)
+		default:
+			ml, err := lex.alph.Val(string(r))
+			if err != nil || ml == 0 {
+				return nil, fmt.Errorf("invalid letter %q in move %q", r, notation)
+			}
+			p.letters = append(p.letters, ml)
+			p.through = append(p.through, inParens)
+		}
+	}
+	if inParens {
+		return nil, fmt.Errorf("unclosed parenthesis in move %q", notation)
+	}
+	if len(p.letters) == 0 {
+		return nil, errors.New("move has no letters")
+	}
+	return p, nil
+}
+
+// placePlay checks that play can be placed on bd from rack: it fits on the
+// board, agrees with the tiles it plays through, is the whole word along
+// its line, places at least one tile, connects to the tiles already down
+// (or covers the center on an empty board), and uses only tiles on the
+// rack. If it can, it returns the move, scored; otherwise it returns why
+// not. Whether the words are valid is left to the caller.
+func placePlay(bd *board.GameBoard, lex *lexicon, play *parsedPlay, rack tilemapping.MachineWord) (*move.Move, []IllegalReason) {
+	var reasons []IllegalReason
+	illegal := func(code, format string, args ...any) {
+		reasons = append(reasons, IllegalReason{code, fmt.Sprintf(format, args...)})
+	}
+
+	dr, dc := 0, 1
+	if play.vertical {
+		dr, dc = 1, 0
+	}
+	n := len(play.letters)
+	if play.row+dr*(n-1) >= boardSize || play.col+dc*(n-1) >= boardSize {
+		illegal("off_board", "the play runs off the board")
+		return nil, reasons
+	}
+	if n < 2 {
+		illegal("too_short", "a play must form a word of at least two letters")
+	}
+
+	inBounds := func(r, c int) bool { return r >= 0 && r < boardSize && c >= 0 && c < boardSize }
+	hasTile := func(r, c int) bool { return inBounds(r, c) && bd.HasLetter(r, c) }
+	tiles := make(tilemapping.MachineWord, n)
+	tilesPlayed, connected, center := 0, false, false
+	for i, ml := range play.letters {
+		r, c := play.row+dr*i, play.col+dc*i
+		onBoard := bd.GetLetter(r, c)
+		switch {
+		case play.through[i] && onBoard == 0:
+			illegal("play_through_mismatch", "%s is empty, so there is nothing to play through", move.ToBoardGameCoords(r, c, true))
+		case play.through[i] && ml != 0 && onBoard.Unblank() != ml.Unblank():
+			illegal("play_through_mismatch", "%s holds %s, not %s", move.ToBoardGameCoords(r, c, true),
+				lex.alph.Letter(onBoard.Unblank()), lex.alph.Letter(ml.Unblank()))
+		case play.through[i]:
+			connected = true
+		case onBoard != 0 && onBoard.Unblank() != ml.Unblank():
+			illegal("occupied", "%s already holds %s", move.ToBoardGameCoords(r, c, true), lex.alph.Letter(onBoard.Unblank()))
+		case onBoard != 0:
+			// Played through, written without parentheses.
+			connected = true
+		default:
+			tiles[i] = ml
+			tilesPlayed++
+			if hasTile(r-dc, c-dr) || hasTile(r+dc, c+dr) {
+				connected = true
+			}
+		}
+		if r == boardSize/2 && c == boardSize/2 {
+			center = true
+		}
+	}
+	if hasTile(play.row-dr, play.col-dc) || hasTile(play.row+dr*n, play.col+dc*n) {
+		illegal("word_continues", "the word continues onto tiles before or after it; include them in the play")
+	}
+	if tilesPlayed == 0 {
+		illegal("no_tiles_placed", "the play places no tiles")
+	}
+	if bd.GetTilesPlayed() == 0 {
+		if !center {
+			illegal("not_on_center", "the first play must cover the center square")
+		}
+	} else if !connected {
+		illegal("not_connected", "the play does not touch any tile on the board")
+	}
+
+	leave, err := tilemapping.Leave(rack, tiles, false)
+	if err != nil {
+		illegal("not_in_rack", "the rack %s doesn't hold the tiles played", rack.UserVisible(lex.alph))
+	}
+	if len(reasons) > 0 {
+		return nil, reasons
+	}
+
+	m := move.NewScoringMove(0, tiles, leave, play.vertical, tilesPlayed, lex.alph, play.row, play.col)
+	words, bonus := scoreBreakdown(m, bd, lex)
+	score := bonus
+	for _, w := range words {
+		score += w.Score
+	}
+	m = move.NewScoringMove(score, tiles, leave, play.vertical, tilesPlayed, lex.alph, play.row, play.col)
+	bag := bagWithTiles(lex, defaultBagSize(lex.ld, bd.GetTilesPlayed(), len(rack)))
+	setEquities([]*move.Move{m}, bd, lex, bag)
+	return m, nil
+}
+
+// boardStrings writes out bd as rows of single-letter strings, "" for an
+// empty square and lowercase for a blank, the same way boards come in.
+func boardStrings(bd *board.GameBoard, lex *lexicon) [][]string {
+	rows := make([][]string, boardSize)
+	for row := range rows {
+		rows[row] = make([]string, boardSize)
+		for col := range rows[row] {
+			if ml := bd.GetLetter(row, col); ml != 0 {
+				rows[row][col] = lex.alph.Letter(ml)
+			}
+		}
+	}
+	return rows
+}
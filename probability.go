@@ -0,0 +1,150 @@
+package main
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/domino14/word-golib/kwg"
+	"github.com/domino14/word-golib/tilemapping"
+)
+
+// StudyInfo is what word-study lists show next to a word: its alphagram
+// (its letters in alphabetical order), the chance of drawing its tiles from
+// a full bag, and where that chance ranks among words of the same length in
+// the lexicon, 1 being the likeliest. Words that are equally likely share
+// a rank.
+type StudyInfo struct {
+	Alphagram       string  `json:"alphagram"`
+	Probability     float64 `json:"probability"`
+	ProbabilityRank int     `json:"probabilityRank"`
+}
+
+// studyInfo returns the StudyInfo of word in lex.
+func studyInfo(lex *lexicon, word tilemapping.MachineWord) *StudyInfo {
+	ways := combinations(lex.ld, word)
+	total := 0
+	for _, n := range lex.ld.Distribution() {
+		total += int(n)
+	}
+	return &StudyInfo{
+		Alphagram:       alphagram(word).UserVisible(lex.alph),
+		Probability:     ways / binomial(total, len(word)),
+		ProbabilityRank: lex.probabilityRank(word, ways),
+	}
+}
+
+// alphagram returns the letters of word, unblanked, in alphabet order.
+func alphagram(word tilemapping.MachineWord) tilemapping.MachineWord {
+	sorted := make(tilemapping.MachineWord, len(word))
+	for i, ml := range word {
+		sorted[i] = ml.Unblank()
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// combinations returns the number of ways to draw the tiles of word from a
+// full bag of ld, counting draws where blanks stand in for some of its
+// letters. Word-study lists rank words by this, the most likely draws
+// first.
+func combinations(ld *tilemapping.LetterDistribution, word tilemapping.MachineWord) float64 {
+	dist := ld.Distribution()
+	used := map[tilemapping.MachineLetter]int{}
+	for _, ml := range word {
+		used[ml.Unblank()]++
+	}
+	// ways[b] counts the draws of natural tiles for the word with b of its
+	// letters left for blanks to fill.
+	ways := []float64{1}
+	for ml, k := range used {
+		next := make([]float64, len(ways)+k)
+		for b, w := range ways {
+			for j := 0; j <= k; j++ {
+				next[b+j] += w * binomial(int(dist[ml]), k-j)
+			}
+		}
+		ways = next
+	}
+	total := 0.0
+	for b, w := range ways {
+		total += w * binomial(int(dist[0]), b)
+	}
+	return total
+}
+
+// probabilityLess returns a less function for sort.SliceStable that orders
+// words by length, shortest first, and then likeliest first.
+func probabilityLess(ld *tilemapping.LetterDistribution) func(a, b tilemapping.MachineWord) bool {
+	ways := map[string]float64{}
+	waysOf := func(word tilemapping.MachineWord) float64 {
+		w, ok := ways[string(word)]
+		if !ok {
+			w = combinations(ld, word)
+			ways[string(word)] = w
+		}
+		return w
+	}
+	return func(a, b tilemapping.MachineWord) bool {
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return waysOf(a) > waysOf(b)
+	}
+}
+
+// binomial returns n choose k.
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	c := 1.0
+	for i := 1; i <= k; i++ {
+		c = c * float64(n-k+i) / float64(i)
+	}
+	return c
+}
+
+// probabilityRanks holds, for each word length, the combinations of every
+// word of that length in a lexicon, likeliest first. It is built the first
+// time a rank is asked for.
+type probabilityRanks struct {
+	once     sync.Once
+	byLength [][]float64
+}
+
+// probabilityRank returns the rank of word, which can be drawn in ways
+// ways, among the words of its length in lex.
+func (lex *lexicon) probabilityRank(word tilemapping.MachineWord, ways float64) int {
+	lex.ranks.once.Do(func() {
+		lex.ranks.byLength = make([][]float64, maxWordLength+1)
+		eachWord(lex.gd, lex.gd.ArcIndex(0), nil, func(w tilemapping.MachineWord) {
+			lex.ranks.byLength[len(w)] = append(lex.ranks.byLength[len(w)], combinations(lex.ld, w))
+		})
+		for _, all := range lex.ranks.byLength {
+			sort.Sort(sort.Reverse(sort.Float64Slice(all)))
+		}
+	})
+	if len(word) >= len(lex.ranks.byLength) {
+		return 0
+	}
+	all := lex.ranks.byLength[len(word)]
+	return sort.Search(len(all), func(i int) bool { return all[i] <= ways }) + 1
+}
+
+// eachWord calls f for every word of at most maxWordLength letters below
+// node in the DAWG half of g that extends word.
+func eachWord(g *kwg.KWG, node uint32, word tilemapping.MachineWord, f func(tilemapping.MachineWord)) {
+	for ; ; node++ {
+		word = append(word, tilemapping.MachineLetter(g.Tile(node)))
+		if g.Accepts(node) {
+			f(word)
+		}
+		if arc := g.ArcIndex(node); arc != 0 && len(word) < maxWordLength {
+			eachWord(g, arc, word, f)
+		}
+		word = word[:len(word)-1]
+		if g.IsEnd(node) {
+			return
+		}
+	}
+}
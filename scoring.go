@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/domino14/word-golib/tilemapping"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/move"
+)
+
+// bingoBonus is the bonus for playing every tile of a full rack.
+const bingoBonus = 50
+
+// premiumNames names the premium squares as players do.
+var premiumNames = map[board.BonusSquare]string{
+	board.Bonus2LS: "DL",
+	board.Bonus3LS: "TL",
+	board.Bonus4LS: "QL",
+	board.Bonus2WS: "DW",
+	board.Bonus3WS: "TW",
+	board.Bonus4WS: "QW",
+}
+
+// PremiumSquare is a premium square a word newly covered, e.g. "H8" "DW".
+type PremiumSquare struct {
+	Square string `json:"square"`
+	Bonus  string `json:"bonus"` // "DL", "TL", "DW", or "TW"
+}
+
+// ScoredWord is one word a play forms: the main word along the play, or a
+// cross-word hooked by one of its tiles. Premiums are the premium squares
+// under the word's new tiles, which are the only ones that count.
+type ScoredWord struct {
+	Word     string          `json:"word"`
+	Position string          `json:"position"` // in move notation, e.g. "8D" across or "D8" down
+	Main     bool            `json:"main"`
+	Score    int             `json:"score"`
+	Premiums []PremiumSquare `json:"premiums"`
+}
+
+// scoreBreakdown splits the score of play m on bd into the words it forms,
+// main word first, and the bingo bonus. The word scores and the bonus add up
+// to m's score. Cross-words are scored from the cross-scores the move
+// generator uses, so bd's cross-sets must be current.
+func scoreBreakdown(m *move.Move, bd *board.GameBoard, lex *lexicon) ([]ScoredWord, int) {
+	row, col, vertical := m.CoordsAndVertical()
+	dr, dc := 0, 1
+	crossDir := board.VerticalDirection
+	if vertical {
+		dr, dc = 1, 0
+		crossDir = board.HorizontalDirection
+	}
+
+	main := ScoredWord{Position: m.BoardCoords(), Main: true, Premiums: []PremiumSquare{}}
+	var word strings.Builder
+	mainSum, mainMult := 0, 1
+	var crossWords []ScoredWord
+	for i, ml := range m.Tiles() {
+		r, c := row+dr*i, col+dc*i
+		fresh := ml != 0
+		if !fresh {
+			ml = bd.GetLetter(r, c)
+		}
+		word.WriteString(lex.alph.Letter(ml.Unblank()))
+		letterScore := 0
+		if !ml.IsBlanked() {
+			letterScore = lex.ld.Score(ml)
+		}
+		if !fresh {
+			mainSum += letterScore
+			continue
+		}
+
+		var premiums []PremiumSquare
+		bonus := bd.GetBonus(r, c)
+		if name, ok := premiumNames[bonus]; ok {
+			premiums = append(premiums, PremiumSquare{Square: move.ToBoardGameCoords(r, c, true), Bonus: name})
+		}
+		sq := bd.GetSqIdx(r, c)
+		lm, wm := bd.GetLetterMultiplier(sq), bd.GetWordMultiplier(sq)
+		mainSum += letterScore * lm
+		mainMult *= wm
+		main.Premiums = append(main.Premiums, premiums...)
+
+		if cross, start := crossWord(bd, lex, r, c, !vertical, ml); cross != "" {
+			if premiums == nil {
+				premiums = []PremiumSquare{}
+			}
+			crossWords = append(crossWords, ScoredWord{
+				Word:     cross,
+				Position: move.ToBoardGameCoords(start[0], start[1], !vertical),
+				Score:    (bd.GetCrossScore(r, c, crossDir) + letterScore*lm) * wm,
+				Premiums: premiums,
+			})
+		}
+	}
+	main.Word = word.String()
+	main.Score = mainSum * mainMult
+
+	bonus := 0
+	if m.TilesPlayed() == rackSize {
+		bonus = bingoBonus
+	}
+	return append([]ScoredWord{main}, crossWords...), bonus
+}
+
+// crossWord returns the word that a new tile ml at (row, col) forms with
+// the tiles next to it in the given direction, and the square it starts
+// on, or "" if the tile has no neighbours that way.
+func crossWord(bd *board.GameBoard, lex *lexicon, row, col int, vertical bool, ml tilemapping.MachineLetter) (string, [2]int) {
+	dr, dc := 0, 1
+	if vertical {
+		dr, dc = 1, 0
+	}
+	inBounds := func(r, c int) bool { return r >= 0 && r < boardSize && c >= 0 && c < boardSize }
+	r, c := row, col
+	for inBounds(r-dr, c-dc) && bd.HasLetter(r-dr, c-dc) {
+		r, c = r-dr, c-dc
+	}
+	start := [2]int{r, c}
+
+	var word strings.Builder
+	n := 0
+	for ; inBounds(r, c) && (bd.HasLetter(r, c) || (r == row && c == col)); r, c = r+dr, c+dc {
+		letter := ml
+		if r != row || c != col {
+			letter = bd.GetLetter(r, c)
+		}
+		word.WriteString(lex.alph.Letter(letter.Unblank()))
+		n++
+	}
+	if n < 2 {
+		return "", start
+	}
+	return word.String(), start
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/domino14/word-golib/tilemapping"
+
+	"github.com/domino14/macondo/board"
+	"github.com/domino14/macondo/cross_set"
+	"github.com/domino14/macondo/move"
+	"github.com/domino14/macondo/movegen"
+)
+
+// The largest Candidates, Plies and Iterations a /simulate request may ask
+// for, so that one request can't run for ever.
+const (
+	maxSimCandidates = 50
+	maxSimPlies      = 10
+	maxSimIterations = 10000
+)
+
+type SimulateRequest struct {
+	Board      [][]BoardCell `json:"board"`                // 15x15 board; lowercase letters are blanks
+	Rack       string        `json:"rack"`                 // Our rack
+	TilePool   string        `json:"tilePool"`             // Unseen tiles: the opponent's rack plus the bag
+	Candidates int           `json:"candidates,omitempty"` // Number of top static plays to simulate (default 10, at most 50)
+	Plies      int           `json:"plies,omitempty"`      // Moves to play out after each candidate (default 2, at most 10)
+	Iterations int           `json:"iterations,omitempty"` // Playouts per candidate (default 500, at most 10000)
+	Spread     *int          `json:"spread,omitempty"`     // Our score minus the opponent's before this move; from the CGP if omitted
+	Lexicon    string        `json:"lexicon,omitempty"`
+	// DeriveTilePool replaces TilePool with the distribution minus the
+	// board minus Rack.
+	DeriveTilePool bool   `json:"deriveTilePool,omitempty"`
+	CGP            string `json:"cgp,omitempty"`  // Position in CGP notation; replaces board
+	Seed           *int64 `json:"seed,omitempty"` // Seeds the playouts; random if omitted
+}
+
+// SimulatedMove is a candidate play with the results of its playouts.
+// MeanSpread is the average points margin the candidate and the simulated
+// replies produce; WinPercent counts a playout as won when Spread plus that
+// margin is positive, with ties counting half.
+type SimulatedMove struct {
+	Move
+	Iterations int     `json:"iterations"`
+	MeanSpread float64 `json:"meanSpread"`
+	StdError   float64 `json:"stdError"`
+	WinPercent float64 `json:"winPercent"`
+}
+
+type SimulateResponse struct {
+	Candidates []SimulatedMove `json:"candidates"`
+	Plies      int             `json:"plies"`
+	Iterations int             `json:"iterations"`
+	Seed       int64           `json:"seed"` // pass back to reproduce this run
+	Lexicon    string          `json:"lexicon"`
+}
+
+func simulateHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SimulateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	pos, err := applyCGP(req.CGP, &req.Board, &req.Rack, &req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	spread := 0
+	if req.Spread != nil {
+		spread = *req.Spread
+	} else if req.CGP != "" {
+		spread = pos.Scores[0] - pos.Scores[1]
+	}
+
+	if req.Rack == "" {
+		http.Error(w, "Rack is required", http.StatusBadRequest)
+		return
+	}
+	if req.TilePool == "" && !req.DeriveTilePool {
+		http.Error(w, "TilePool is required", http.StatusBadRequest)
+		return
+	}
+	if err := checkBoardShape(req.Board); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Candidates <= 0 {
+		req.Candidates = 10
+	}
+	if req.Plies <= 0 {
+		req.Plies = 2
+	}
+	if req.Iterations <= 0 {
+		req.Iterations = 500
+	}
+	if req.Candidates > maxSimCandidates {
+		http.Error(w, fmt.Sprintf("at most %d candidates are allowed", maxSimCandidates), http.StatusBadRequest)
+		return
+	}
+	if req.Plies > maxSimPlies {
+		http.Error(w, fmt.Sprintf("at most %d plies are allowed", maxSimPlies), http.StatusBadRequest)
+		return
+	}
+	if req.Iterations > maxSimIterations {
+		http.Error(w, fmt.Sprintf("at most %d iterations are allowed", maxSimIterations), http.StatusBadRequest)
+		return
+	}
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bd, err := loadBoard(req.Board, lex)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tilePool := req.TilePool
+	if req.DeriveTilePool {
+		counts, err := unseenTiles(lex, bd, req.Rack)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tilePool = poolString(lex, counts)
+	}
+	unseen, err := tilemapping.ToMachineLetters(strings.ToUpper(strings.ReplaceAll(tilePool, " ", "")), lex.alph)
+	if err != nil {
+		http.Error(w, "Invalid tilePool", http.StatusBadRequest)
+		return
+	}
+	rackTiles, err := tilemapping.ToMachineLetters(strings.ToUpper(strings.ReplaceAll(req.Rack, " ", "")), lex.alph)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid rack %q", req.Rack), http.StatusBadRequest)
+		return
+	}
+	rack := tilemapping.NewRack(lex.alph)
+	rack.Set(rackTiles)
+	generator := movegen.NewGordonGenerator(lex.gd, bd, lex.ld)
+	moves := generator.GenAll(rack, false)
+
+	// Pick candidates by static equity; the opponent holds up to a full
+	// rack of the unseen tiles and the rest are in the bag.
+	bagSize := len(unseen) - rackSize
+	if bagSize < 0 {
+		bagSize = 0
+	}
+	setEquities(moves, bd, lex, bagWithTiles(lex, bagSize))
+	sortByEquity(moves)
+	if len(moves) > req.Candidates {
+		moves = moves[:req.Candidates]
+	}
+
+	// Playouts draw from a request-local RNG so that a seed reproduces the
+	// run exactly.
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	fmt.Printf("Simulating %d candidates for rack '%s' (%d plies, %d iterations)\n",
+		len(moves), req.Rack, req.Plies, req.Iterations)
+
+	candidates := make([]SimulatedMove, 0, len(moves))
+	for _, m := range moves {
+		sim, err := simulateCandidate(r.Context(), lex, bd, m, rng, unseen, req.Plies, req.Iterations, spread)
+		if err != nil {
+			return // The client went away.
+		}
+		sim.Move = moveToResponse(m, bd, lex)
+		candidates = append(candidates, sim)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].WinPercent != candidates[j].WinPercent {
+			return candidates[i].WinPercent > candidates[j].WinPercent
+		}
+		return candidates[i].MeanSpread > candidates[j].MeanSpread
+	})
+
+	response := SimulateResponse{
+		Candidates: candidates,
+		Plies:      req.Plies,
+		Iterations: req.Iterations,
+		Seed:       seed,
+		Lexicon:    lex.name,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// simulateCandidate plays cand on a copy of bd and then, iterations times,
+// shuffles unseen with rng, deals the opponent a rack from it, refills our
+// rack, and has both sides alternate their best static-equity play for the
+// given number of plies. Each playout's spread is the candidate's score
+// plus our later scores minus the opponent's. If ctx is cancelled it stops
+// and returns ctx's error.
+func simulateCandidate(ctx context.Context, lex *lexicon, bd *board.GameBoard, cand *move.Move, rng *rand.Rand,
+	unseen []tilemapping.MachineLetter, plies, iterations, spread int) (SimulatedMove, error) {
+
+	start := bd.Copy()
+	start.PlayMove(cand)
+	cross_set.UpdateCrossSetsForMove(start, cand, lex.gd, lex.ld)
+
+	sim := bd.Copy()
+	generator := movegen.NewGordonGenerator(lex.gd, sim, lex.ld)
+	rack := tilemapping.NewRack(lex.alph)
+	pool := make([]tilemapping.MachineLetter, len(unseen))
+
+	var sum, sumSq, wins float64
+	for i := 0; i < iterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return SimulatedMove{}, err
+		}
+		sim.CopyFrom(start)
+		copy(pool, unseen)
+		rng.Shuffle(len(pool), func(a, b int) {
+			pool[a], pool[b] = pool[b], pool[a]
+		})
+
+		// racks[0] is the opponent, who moves first; racks[1] is us.
+		bag := pool
+		racks := [2][]tilemapping.MachineLetter{}
+		racks[0], bag = drawTiles(bag, nil, rackSize)
+		racks[1], bag = drawTiles(bag, cand.Leave(), rackSize)
+
+		points := float64(cand.Score())
+		for ply := 0; ply < plies; ply++ {
+			mover := ply % 2
+			sign := -1.0
+			if mover == 1 {
+				sign = 1.0
+			}
+
+			rack.Set(racks[mover])
+			best := bestStaticMove(generator.GenAll(rack, false), sim, lex, len(bag))
+			if best == nil || best.Action() != move.MoveTypePlay {
+				continue
+			}
+			points += sign * float64(best.Score())
+			sim.PlayMove(best)
+			cross_set.UpdateCrossSetsForMove(sim, best, lex.gd, lex.ld)
+			racks[mover], bag = drawTiles(bag, best.Leave(), rackSize)
+
+			// Going out ends the game: the mover collects twice the value
+			// of the tiles left on the other rack.
+			if len(racks[mover]) == 0 {
+				other := tilemapping.MachineWord(racks[1-mover])
+				points += sign * 2 * float64(other.Score(lex.ld))
+				break
+			}
+		}
+
+		sum += points
+		sumSq += points * points
+		switch final := float64(spread) + points; {
+		case final > 0:
+			wins++
+		case final == 0:
+			wins += 0.5
+		}
+	}
+
+	n := float64(iterations)
+	mean := sum / n
+	stdErr := 0.0
+	if iterations > 1 {
+		variance := (sumSq - n*mean*mean) / (n - 1)
+		stdErr = math.Sqrt(math.Max(variance, 0) / n)
+	}
+	return SimulatedMove{
+		Iterations: iterations,
+		MeanSpread: mean,
+		StdError:   stdErr,
+		WinPercent: wins / n * 100.0,
+	}, nil
+}
+
+// drawTiles returns a new rack made of keep plus tiles from the front of bag
+// until it holds size tiles, along with what is left of the bag.
+func drawTiles(bag []tilemapping.MachineLetter, keep tilemapping.MachineWord, size int) ([]tilemapping.MachineLetter, []tilemapping.MachineLetter) {
+	rack := append(make([]tilemapping.MachineLetter, 0, size), keep...)
+	n := size - len(rack)
+	if n > len(bag) {
+		n = len(bag)
+	}
+	if n > 0 {
+		rack = append(rack, bag[:n]...)
+		bag = bag[n:]
+	}
+	return rack, bag
+}
+
+// bestStaticMove returns the move with the highest static equity given how
+// many tiles remain in the bag, or nil if there are no moves.
+func bestStaticMove(moves []*move.Move, bd *board.GameBoard, lex *lexicon, bagSize int) *move.Move {
+	if len(moves) == 0 {
+		return nil
+	}
+	setEquities(moves, bd, lex, bagWithTiles(lex, bagSize))
+	best := moves[0]
+	for _, m := range moves[1:] {
+		if m.Equity() > best.Equity() {
+			best = m
+		}
+	}
+	return best
+}
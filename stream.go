@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// defaultProgressEvery is how many iterations pass between progress events
+// when the request doesn't say.
+const defaultProgressEvery = 100
+
+// BulkProgressEvent is the data of a "progress" event on
+// /bulk-move-gen/stream. The averages are over the iterations done so far,
+// counted as BulkMoveGenResponse counts them.
+type BulkProgressEvent struct {
+	Done         int     `json:"done"`
+	Total        int     `json:"total"`
+	AverageScore float64 `json:"averageScore"`
+	BingoPercent float64 `json:"bingoPercent"`
+}
+
+// bulkMoveGenStreamHandler runs a /bulk-move-gen request and reports on it
+// as Server-Sent Events: a "progress" event every ProgressEvery iterations
+// and a final "result" event holding the BulkMoveGenResponse. The work stops
+// if the client disconnects. The request is a POST with a JSON body, so
+// EventSource can't open it; clients use fetch and read the response body
+// as a stream.
+func bulkMoveGenStreamHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BulkMoveGenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if _, err := applyCGP(req.CGP, &req.Board, &req.Rack, &req.Lexicon); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	run, err := newBulkRun(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	every := req.ProgressEvery
+	if every <= 0 {
+		every = defaultProgressEvery
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // keep reverse proxies from buffering events
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+
+	fmt.Printf("Streaming bulk move generation with %d iterations on %d workers...\n", run.iterations, run.workers)
+	var done, evaluated, totalScore, bingos int
+	response, err := run.run(r.Context(), func(sample bulkSample) {
+		done++
+		if sample.evaluated {
+			evaluated++
+			totalScore += sample.score
+			if sample.bingo {
+				bingos++
+			}
+		}
+		if done%every != 0 || done == run.iterations {
+			return
+		}
+		event := BulkProgressEvent{Done: done, Total: run.iterations}
+		if evaluated > 0 {
+			event.AverageScore = float64(totalScore) / float64(evaluated)
+			event.BingoPercent = float64(bingos) / float64(evaluated) * 100.0
+		}
+		writeEvent(w, rc, "progress", event)
+	})
+	if err != nil {
+		fmt.Println("Bulk move generation stream stopped: client disconnected")
+		return
+	}
+	writeEvent(w, rc, "result", response)
+}
+
+// writeEvent sends v as JSON in a Server-Sent Event of the given type and
+// flushes it to the client.
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	rc.Flush()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/domino14/word-golib/tilemapping"
+
+	"github.com/domino14/macondo/board"
+)
+
+type UnseenTilesRequest struct {
+	Board   [][]BoardCell `json:"board"`          // 15x15 board; lowercase letters are blanks
+	Rack    string        `json:"rack,omitempty"` // Known rack to exclude from the pool
+	Lexicon string        `json:"lexicon,omitempty"`
+	CGP     string        `json:"cgp,omitempty"` // Position in CGP notation; replaces board
+}
+
+// UnseenTilesResponse gives the unseen tiles per letter ("?" for blanks)
+// and as a TilePool string that can be passed to /bulk-move-gen.
+type UnseenTilesResponse struct {
+	Tiles    map[string]int `json:"tiles"`
+	Total    int            `json:"total"`
+	TilePool string         `json:"tilePool"`
+	Lexicon  string         `json:"lexicon"`
+}
+
+func unseenTilesHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UnseenTilesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if _, err := applyCGP(req.CGP, &req.Board, &req.Rack, &req.Lexicon); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := checkBoardShape(req.Board); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lex, err := getLexicon(req.Lexicon)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bd, err := loadBoard(req.Board, lex)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	counts, err := unseenTiles(lex, bd, req.Rack)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tiles := make(map[string]int)
+	total := 0
+	for ml, n := range counts {
+		if n > 0 {
+			tiles[lex.alph.Letter(tilemapping.MachineLetter(ml))] = n
+			total += n
+		}
+	}
+
+	response := UnseenTilesResponse{
+		Tiles:    tiles,
+		Total:    total,
+		TilePool: poolString(lex, counts),
+		Lexicon:  lex.name,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// unseenTiles counts, per machine letter (blanks at 0), the tiles of lex's
+// distribution that are neither on bd nor on rack. It is an error for the
+// board and rack to hold more of a tile than the distribution has.
+func unseenTiles(lex *lexicon, bd *board.GameBoard, rack string) ([]int, error) {
+	dist := lex.ld.Distribution()
+	counts := make([]int, len(dist))
+	for ml, n := range dist {
+		counts[ml] = int(n)
+	}
+
+	for row := 0; row < boardSize; row++ {
+		for col := 0; col < boardSize; col++ {
+			if ml := bd.GetLetter(row, col); ml != 0 {
+				// Blanked letters count against the blanks.
+				counts[ml.IntrinsicTileIdx()]--
+			}
+		}
+	}
+	rackTiles, err := tilemapping.ToMachineLetters(strings.ToUpper(strings.ReplaceAll(rack, " ", "")), lex.alph)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rack %q", rack)
+	}
+	for _, ml := range rackTiles {
+		counts[ml]--
+	}
+
+	for ml, n := range counts {
+		if n < 0 {
+			return nil, fmt.Errorf("board and rack have %d %s tiles but the distribution only has %d",
+				int(dist[ml])-n, lex.alph.Letter(tilemapping.MachineLetter(ml)), dist[ml])
+		}
+	}
+	return counts, nil
+}
+
+// poolString spells out per-letter counts as a tile pool string such as
+// "AAB?".
+func poolString(lex *lexicon, counts []int) string {
+	var sb strings.Builder
+	for ml, n := range counts {
+		sb.WriteString(strings.Repeat(lex.alph.Letter(tilemapping.MachineLetter(ml)), n))
+	}
+	return sb.String()
+}